@@ -1,23 +1,32 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type ContextKey string
 
 const (
 	// Context keys
-	UserIDKey         ContextKey = "userID"
-	CartIDKey         ContextKey = "cartID"
-	SessionIDKey      ContextKey = "sessionID"
-	TempSessionIDKey  ContextKey = "temporarySessionID"
-	CartContentKey    ContextKey = "cartContent"
-	ProductDetailsKey ContextKey = "productDetails"
-
-	// Staff discount
+	UserIDKey          ContextKey = "userID"
+	StaffPermissionKey ContextKey = "staffPermission"
+	CartIDKey          ContextKey = "cartID"
+	SessionIDKey       ContextKey = "sessionID"
+	ReauthenticatedKey ContextKey = "reauthenticatedAt"
+	RequestIDKey       ContextKey = "requestID"
+	TempSessionIDKey   ContextKey = "temporarySessionID"
+	CartContentKey     ContextKey = "cartContent"
+	ProductDetailsKey  ContextKey = "productDetails"
+
+	// Staff discount defaults, until other values are saved through the admin API (see package settings)
 	StaffDiscountRate = 0.25 // 25% discount for staff members
 	StaffMonthlyQuota = 3    // Maximum number of items staff can buy at a discount per month
 
-	// Member discount tiers
+	// Member discount tier defaults, until tiers are saved through the admin API (see package memberdiscount)
 	MemberDiscountTier1Min = 3000  // £30.00
 	MemberDiscountTier2Min = 6000  // £60.00
 	MemberDiscountTier3Min = 9000  // £90.00
@@ -41,18 +50,98 @@ var (
 	APIPort       = os.Getenv("FM_ADMIN_API_PORT")
 	Pepper        = os.Getenv("FM_USER_PASSWORD_PEPPER")
 
+	// Additional peppers as "version:pepper,version:pepper". FM_USER_PASSWORD_PEPPER is always version 0,
+	// and new hashes use PepperVersion.
+	Peppers       = parsePeppers(os.Getenv("FM_USER_PASSWORD_PEPPERS"))
+	PepperVersion = getEnvInt("FM_USER_PASSWORD_PEPPER_VERSION", 0)
+
+	// Argon2id parameters for new hashes. Stored hashes with weaker parameters are upgraded at sign-in.
+	Argon2Time      = getEnvInt("FM_ARGON2_TIME", 1)
+	Argon2MemoryKiB = getEnvInt("FM_ARGON2_MEMORY_KIB", 64*1024)
+	Argon2Threads   = getEnvInt("FM_ARGON2_THREADS", 4)
+
 	SessionIDSecretKey = os.Getenv("FM_SESSION_ID_SECRET_KEY")
 
+	// Session ID key ring as "keyID:key,keyID:key". FM_SESSION_ID_SECRET_KEY is always key ID "0",
+	// and new cookies are encrypted with SessionIDActiveKeyID.
+	SessionIDSecretKeys  = parseSessionIDKeys(os.Getenv("FM_SESSION_ID_SECRET_KEYS"))
+	SessionIDActiveKeyID = getEnvDefault("FM_SESSION_ID_ACTIVE_KEY_ID", "0")
+	// Cookies from before the key ring, with no key ID, are only accepted until this RFC 3339 time.
+	// Unset, they are refused. No session outlives the remember-device absolute timeout, so there is
+	// never a reason to set it further ahead than that.
+	SessionIDLegacyTokensUntil = parseTime(os.Getenv("FM_SESSION_ID_ACCEPT_LEGACY_UNTIL"))
+
+	// Key for signing CSRF tokens, state-changing browser requests are refused while it is unset
+	CSRFSecretKey = os.Getenv("FM_CSRF_SECRET_KEY")
+
+	// Requests from the SSR server are trusted when they are signed with this key (see package s2s),
+	// or present a client certificate with one of these common names
+	S2SSharedSecret    = os.Getenv("FM_S2S_SHARED_SECRET")
+	S2SClientCertNames = parseList(os.Getenv("FM_S2S_CLIENT_CERT_NAMES"))
+	// How far a signed request's timestamp may be from our clock
+	S2SSignatureWindow = time.Duration(getEnvInt("FM_S2S_SIGNATURE_WINDOW_SECONDS", 30)) * time.Second
+
+	// Serve HTTPS directly, verifying client certificates against the CA bundle when one is given
+	TLSCertFile     = os.Getenv("FM_TLS_CERT_FILE")
+	TLSKeyFile      = os.Getenv("FM_TLS_KEY_FILE")
+	TLSClientCAFile = os.Getenv("FM_TLS_CLIENT_CA_FILE")
+
+	// Optional token that must be sent as X-Bootstrap-Token to create the first employee
+	BootstrapToken = os.Getenv("FM_BOOTSTRAP_TOKEN")
+
+	// Set when the API sits behind a proxy that sets X-Forwarded-For
+	TrustProxyHeaders = os.Getenv("FM_TRUST_PROXY_HEADERS") == "true"
+	// Number of trusted proxies in front of the API, each appends one X-Forwarded-For entry
+	TrustedProxyHops = max(getEnvInt("FM_TRUSTED_PROXY_HOPS", 1), 1)
+
 	RedisCachePrefixCart    = os.Getenv("FM_REDIS_CACHE_CART_PREFIX")
 	RedisCachePrefixProduct = os.Getenv("FM_REDIS_CACHE_PRODUCT_PREFIX")
 
 	IsProduction = os.Getenv("FM_ENV_MODE") == "production"
 
+	// Base URL of the admin frontend, used for links in emails
+	AdminSiteURL = os.Getenv("FM_ADMIN_SITE_URL")
+
+	TOTPIssuer = "FM Admin"
+
+	RedisKeyPrefixPendingSignIn     = "admin:pending-sign-in:"
+	RedisKeyPrefixSignInFailures    = "admin:sign-in-failures:"
+	RedisKeyPrefixSignInLockout     = "admin:sign-in-lockout:"
+	RedisKeyPrefixPasswordReset     = "admin:password-reset:"
+	RedisKeySessionReaperLock       = "admin:lock:session-reaper"
+	RedisKeyPrefixSessionCache      = "admin:session:"
+	RedisKeyPrefixUserSessions      = "admin:user-sessions:"
+	RedisKeyPrefixSessionGeneration = "admin:session-generation:"
+	RedisKeyPrefixS2SSignature      = "admin:s2s-signature:"
+
+	// Session lifetimes for staff, and for privileged staff (see sessionpolicy.PrivilegedPermission)
+	SessionIdleTimeout                     = time.Duration(getEnvInt("FM_SESSION_IDLE_MINUTES", 15)) * time.Minute
+	SessionAbsoluteTimeout                 = time.Duration(getEnvInt("FM_SESSION_ABSOLUTE_HOURS", 12)) * time.Hour
+	SessionRememberDeviceIdleTimeout       = time.Duration(getEnvInt("FM_SESSION_REMEMBER_DEVICE_IDLE_HOURS", 72)) * time.Hour
+	SessionRememberDeviceAbsoluteTimeout   = time.Duration(getEnvInt("FM_SESSION_REMEMBER_DEVICE_ABSOLUTE_DAYS", 14)) * 24 * time.Hour
+	PrivilegedSessionIdleTimeout           = time.Duration(getEnvInt("FM_PRIVILEGED_SESSION_IDLE_MINUTES", 10)) * time.Minute
+	PrivilegedSessionAbsoluteTimeout       = time.Duration(getEnvInt("FM_PRIVILEGED_SESSION_ABSOLUTE_HOURS", 8)) * time.Hour
+	PrivilegedSessionRememberDeviceAllowed = os.Getenv("FM_PRIVILEGED_SESSION_REMEMBER_DEVICE") == "true"
+
+	// How long after entering their password or TOTP code staff may perform sensitive actions
+	ReauthenticationWindow = time.Duration(getEnvInt("FM_REAUTHENTICATION_WINDOW_MINUTES", 5)) * time.Minute
+
+	// How often expired staff sessions are deleted
+	SessionReaperInterval = time.Duration(getEnvPositiveInt("FM_SESSION_REAPER_INTERVAL_SECONDS", 60)) * time.Second
+
+	// Sign-in brute-force protection
+	SignInFailureWindow     = time.Duration(getEnvInt("FM_SIGN_IN_FAILURE_WINDOW_MINUTES", 15)) * time.Minute
+	SignInMaxEmailFailures  = getEnvInt("FM_SIGN_IN_MAX_EMAIL_FAILURES", 10)
+	SignInMaxIPFailures     = getEnvInt("FM_SIGN_IN_MAX_IP_FAILURES", 50)
+	SignInLockoutDuration   = time.Duration(getEnvInt("FM_SIGN_IN_LOCKOUT_MINUTES", 30)) * time.Minute
+	SignInDelayFreeAttempts = 3
+
 	HighPriorityEmailQueue = "high_priority_email_queue"
 	LowPriorityEmailQueue  = "low_priority_email_queue"
 )
 
-// GetMemberDiscountRate returns the appropriate discount rate based on the accumulated value
+// GetMemberDiscountRate returns the appropriate discount rate based on the accumulated value under the default tiers.
+// Use memberdiscount.FetchTiers and memberdiscount.RateFor for the tiers currently in force.
 func GetMemberDiscountRate(accumulatedValue int) float64 {
 	switch {
 	case accumulatedValue >= MemberDiscountTier4Min:
@@ -67,3 +156,83 @@ func GetMemberDiscountRate(accumulatedValue int) float64 {
 		return 0
 	}
 }
+
+// getEnvInt reads an integer environment variable, falling back to the default when it is missing or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvPositiveInt is getEnvInt for values that must be above zero, such as intervals
+func getEnvPositiveInt(key string, fallback int) int {
+	value := getEnvInt(key, fallback)
+	if value <= 0 {
+		log.Printf("%s must be above zero, using %d", key, fallback)
+		return fallback
+	}
+	return value
+}
+
+// getEnvDefault reads an environment variable, falling back to the default when it is missing
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseTime reads an RFC 3339 time, an empty or invalid value is the zero time
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if value != "" {
+			log.Printf("Ignoring invalid time %q: %v", value, err)
+		}
+		return time.Time{}
+	}
+	return t
+}
+
+func parseSessionIDKeys(value string) map[string]string {
+	keys := map[string]string{"0": SessionIDSecretKey}
+	for _, entry := range strings.Split(value, ",") {
+		keyID, key, found := strings.Cut(entry, ":")
+		keyID = strings.TrimSpace(keyID)
+		// The key ID is separated from the ciphertext by a dot
+		if !found || keyID == "" || strings.Contains(keyID, ".") {
+			continue
+		}
+		keys[keyID] = key
+	}
+	return keys
+}
+
+func parsePeppers(value string) map[int]string {
+	peppers := map[int]string{0: Pepper}
+	for _, entry := range strings.Split(value, ",") {
+		version, pepper, found := strings.Cut(entry, ":")
+		if !found {
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(version))
+		if err != nil {
+			continue
+		}
+		peppers[v] = pepper
+	}
+	return peppers
+}
+
+// parseList splits a comma separated value, dropping empty entries
+func parseList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -1,8 +1,12 @@
 package addproduct
 
-import "server-api-admin/util/router"
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
 
 func Listen() {
-	router.Router.POST("/admin/add-product", addProduct)
-	router.Router.GET("/admin/add-product-init", addProductInit)
+	router.Router.POST("/admin/add-product", middlewares.Middleware(addProduct, permissions.EditProducts))
+	router.Router.GET("/admin/add-product-init", middlewares.Middleware(addProductInit, permissions.EditProducts))
 }
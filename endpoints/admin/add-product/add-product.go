@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"server-api-admin/util/audit"
 	"server-api-admin/util/postgresdb"
 	"strings"
 
@@ -148,6 +149,15 @@ func addProduct(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
+	product.ProductID = strings.ToUpper(product.ProductID)
+	entry := audit.NewEntry(r, "add_product", "product", product.ProductID)
+	entry.After = product
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	tx.Commit()
 
 	// Send a success response
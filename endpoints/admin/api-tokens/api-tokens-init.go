@@ -0,0 +1,30 @@
+package apitokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/apitokens"
+	"server-api-admin/util/postgresdb"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func apiTokensInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	apiTokens, err := apitokens.FetchUserTokens(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiTokens": apiTokens,
+	})
+}
@@ -0,0 +1,13 @@
+package apitokens
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/api-tokens-init", middlewares.Middleware(apiTokensInit, permissions.SignedIn))
+	router.Router.POST("/admin/create-api-token", middlewares.Middleware(middlewares.RequireRecentAuth(createAPIToken), permissions.SignedIn))
+	router.Router.POST("/admin/revoke-api-token", middlewares.Middleware(revokeAPIToken, permissions.SignedIn))
+}
@@ -0,0 +1,96 @@
+package apitokens
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/apitokens"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const maxNameLength = 100
+
+type CreateAPITokenRequest struct {
+	Name            string                 `json:"name"`
+	StaffPermission permissions.Permission `json:"staffPermission"`
+	// Unix milliseconds, 0 for a token that doesn't expire
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func createAPIToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	actorPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	var req CreateAPITokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.StaffPermission <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Name) > maxNameLength {
+		http.Error(w, "Name is too long", http.StatusBadRequest)
+		return
+	}
+
+	// A token can only carry bits its owner holds
+	if !actorPermission.Has(req.StaffPermission) {
+		http.Error(w, "Insufficient permission", http.StatusForbidden)
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != 0 {
+		expiresAt.Valid = true
+		expiresAt.Time = time.UnixMilli(req.ExpiresAt)
+		if expiresAt.Time.Before(time.Now()) {
+			http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	token, apiToken, err := apitokens.Create(r.Context(), tx, userID, req.Name, req.StaffPermission, expiresAt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "create_api_token", "staff_api_token", apiToken.TokenID)
+	entry.After = map[string]interface{}{
+		"name":            apiToken.Name,
+		"staffPermission": apiToken.StaffPermission,
+		"expiresAt":       apiToken.ExpiresAt,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The token is only ever returned here
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":    token,
+		"apiToken": apiToken,
+	})
+}
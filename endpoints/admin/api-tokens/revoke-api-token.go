@@ -0,0 +1,59 @@
+package apitokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/apitokens"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RevokeAPITokenRequest struct {
+	TokenID string `json:"tokenID"`
+}
+
+func revokeAPIToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req RevokeAPITokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.TokenID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	revoked, err := apitokens.Revoke(r.Context(), tx, userID, req.TokenID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "API token not found", http.StatusNotFound)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "revoke_api_token", "staff_api_token", req.TokenID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
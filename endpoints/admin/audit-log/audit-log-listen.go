@@ -0,0 +1,11 @@
+package auditlog
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.POST("/admin/audit-log", middlewares.Middleware(auditLog, permissions.ViewAuditLog))
+}
@@ -0,0 +1,75 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+type Request struct {
+	ActorID    string `json:"actorID"`
+	EntityType string `json:"entityType"`
+	EntityID   string `json:"entityID"`
+	// Unix milliseconds, 0 for no bound
+	From     int64 `json:"from"`
+	To       int64 `json:"to"`
+	BeforeID int64 `json:"beforeID"`
+	Limit    int   `json:"limit"`
+}
+
+func auditLog(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := audit.Filter{
+		ActorID:    req.ActorID,
+		EntityType: req.EntityType,
+		EntityID:   req.EntityID,
+		BeforeID:   req.BeforeID,
+		Limit:      req.Limit,
+	}
+	if req.From != 0 {
+		filter.From = time.UnixMilli(req.From)
+	}
+	if req.To != 0 {
+		filter.To = time.UnixMilli(req.To)
+	}
+	if filter.Limit <= 0 || filter.Limit > maxLimit {
+		filter.Limit = defaultLimit
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	entries, err := audit.FetchEntries(r.Context(), tx, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	// Entries are returned newest first, the last ID is the cursor for the next page
+	var nextBeforeID int64
+	if len(entries) == filter.Limit {
+		nextBeforeID = entries[len(entries)-1].AuditID
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries":      entries,
+		"nextBeforeID": nextBeforeID,
+	})
+}
@@ -0,0 +1,10 @@
+package csrftoken
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/csrf-token", middlewares.Middleware(csrfToken))
+}
@@ -0,0 +1,38 @@
+package csrftoken
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/csrf"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// csrfToken issues a token for the current session, to be sent as X-CSRF-Token. Tokens issued
+// before signing in stop working once a session starts, sign-in returns a new one.
+func csrfToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	var err error
+	binding := csrf.Binding(r, sessionID)
+	if binding == "" {
+		binding, err = csrf.SetAnonymousID(w)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	token, err := csrf.Generate(binding)
+	if err != nil {
+		log.Printf("Error generating CSRF token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"csrfToken": token,
+	})
+}
@@ -1,8 +1,12 @@
 package editproduct
 
-import "server-api-admin/util/router"
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
 
 func Listen() {
-	router.Router.GET("/admin/edit-product-init/:product-id", editProductInit)
-	router.Router.POST("/admin/edit-product", editProduct)
+	router.Router.GET("/admin/edit-product-init/:product-id", middlewares.Middleware(editProductInit, permissions.EditProducts))
+	router.Router.POST("/admin/edit-product", middlewares.Middleware(editProduct, permissions.EditProducts))
 }
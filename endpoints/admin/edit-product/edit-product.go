@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/middlewares"
 	"server-api-admin/util/postgresdb"
 	"strings"
 	"time"
@@ -44,6 +46,21 @@ func editProduct(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
 	defer tx.Rollback()
 
+	// The current price and images are about to be replaced, keep them for the audit log
+	previous, err := fetchProductState(r.Context(), tx, productID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Retiring takes the product off sale, so it needs the password to have been entered recently
+	if product.IsRetired && !previous.IsRetired && !middlewares.CheckRecentAuth(w, r) {
+		return
+	}
+
 	var retiredAt sql.NullTime
 	if product.IsRetired {
 		retiredAt.Valid = true
@@ -206,6 +223,16 @@ func editProduct(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		}
 	}
 
+	product.ProductID = productID
+	entry := audit.NewEntry(r, "edit_product", "product", productID)
+	entry.Before = previous
+	entry.After = product
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	tx.Commit()
 
 	w.WriteHeader(http.StatusOK)
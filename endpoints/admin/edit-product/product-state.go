@@ -0,0 +1,89 @@
+package editproduct
+
+import (
+	"context"
+	"database/sql"
+)
+
+// fetchProductState locks the product and returns it in the shape of an edit request,
+// so the audit log can record what the edit replaced
+func fetchProductState(ctx context.Context, tx *sql.Tx, productID string) (Product, error) {
+	product := Product{
+		ProductID:    productID,
+		PublicImages: make([]string, 0),
+		AdminImages:  make([]string, 0),
+	}
+
+	err := tx.QueryRowContext(
+		ctx,
+		`
+			SELECT name, price, material_id, metal_color_id, description, url, product_type_id, is_retired
+			FROM product
+			WHERE product_id = $1
+			FOR UPDATE
+		`,
+		productID,
+	).Scan(
+		&product.Name,
+		&product.Price,
+		&product.MaterialID,
+		&product.MetalColorID,
+		&product.Description,
+		&product.URL,
+		&product.ProductTypeID,
+		&product.IsRetired,
+	)
+	if err != nil {
+		return product, err
+	}
+
+	// public images
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT file_name, ext, catalogue FROM product_image WHERE product_id = $1 ORDER BY sort_order ASC",
+		productID,
+	)
+	if err != nil {
+		return product, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var filename, ext string
+		var inCatalogue bool
+		err = rows.Scan(&filename, &ext, &inCatalogue)
+		if err != nil {
+			return product, err
+		}
+
+		if inCatalogue {
+			filename = "*" + filename
+		}
+		product.PublicImages = append(product.PublicImages, filename+ext)
+	}
+
+	// admin images
+	rows, err = tx.QueryContext(
+		ctx,
+		"SELECT file_name, ext FROM admin_product_image WHERE product_id = $1 ORDER BY sort_order ASC",
+		productID,
+	)
+	if err != nil {
+		return product, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var filename, ext string
+		err = rows.Scan(&filename, &ext)
+		if err != nil {
+			return product, err
+		}
+
+		product.AdminImages = append(product.AdminImages, filename+ext)
+	}
+
+	return product, rows.Err()
+}
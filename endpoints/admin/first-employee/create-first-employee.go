@@ -1,20 +1,34 @@
 package firstemployee
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
 	"server-api-admin/util/password"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/postgresdb"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// Advisory lock key serialising concurrent bootstrap attempts
+const bootstrapLockKey = 7301
+
 type Request struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// createFirstEmployee bootstraps the first staff user. It refuses once any staff user exists,
+// which also makes the optional bootstrap token single use.
 func createFirstEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if config.BootstrapToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Bootstrap-Token")), []byte(config.BootstrapToken)) != 1 {
+		http.Error(w, "Invalid bootstrap token", http.StatusForbidden)
+		return
+	}
+
 	var req Request
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -27,22 +41,45 @@ func createFirstEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	// Held until the transaction ends, so concurrent calls check and insert one at a time
+	_, err = tx.ExecContext(r.Context(), "SELECT pg_advisory_xact_lock($1)", bootstrapLockKey)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var staffExists bool
+	err = tx.QueryRowContext(
+		r.Context(),
+		`SELECT EXISTS (SELECT 1 FROM "user" WHERE staff_permission > 0)`,
+	).Scan(&staffExists)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if staffExists {
+		http.Error(w, "A staff user already exists", http.StatusConflict)
+		return
+	}
+
 	completeHash, encodedSalt, err := password.GeneratePasswordHash(req.Password)
 	if err != nil {
 		http.Error(w, "Failed to generate password hash", http.StatusInternalServerError)
 		return
 	}
 
-	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
-	defer tx.Rollback()
-
 	var userID string
 	err = tx.QueryRowContext(
 		r.Context(),
-		`INSERT INTO "user" (email, password_hash, salt, staff_permission, is_newsletter_subscribed, email_verified) VALUES ($1, $2, $3, 9223372036854775807, TRUE, TRUE) RETURNING user_id`,
+		`INSERT INTO "user" (email, password_hash, salt, staff_permission, is_newsletter_subscribed, email_verified) VALUES ($1, $2, $3, $4, TRUE, TRUE) RETURNING user_id`,
 		req.Email,
 		completeHash,
 		encodedSalt,
+		permissions.All,
 	).Scan(&userID)
 	if err != nil {
 		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
@@ -59,6 +96,17 @@ func createFirstEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
+	entry := audit.NewEntry(r, "create_first_employee", "user", userID)
+	entry.After = map[string]interface{}{
+		"email":           req.Email,
+		"staffPermission": permissions.All,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
@@ -1,7 +1,10 @@
 package firstemployee
 
-import "server-api-admin/util/router"
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/router"
+)
 
 func Listen() {
-	router.Router.POST("/admin/first-employee", createFirstEmployee)
+	router.Router.POST("/admin/first-employee", middlewares.Middleware(createFirstEmployee))
 }
@@ -2,22 +2,44 @@ package admin
 
 import (
 	addproduct "server-api-admin/endpoints/admin/add-product"
+	apitokens "server-api-admin/endpoints/admin/api-tokens"
+	auditlog "server-api-admin/endpoints/admin/audit-log"
+	csrftoken "server-api-admin/endpoints/admin/csrf-token"
 	editproduct "server-api-admin/endpoints/admin/edit-product"
 	firstemployee "server-api-admin/endpoints/admin/first-employee"
+	memberdiscount "server-api-admin/endpoints/admin/member-discount"
+	"server-api-admin/endpoints/admin/metrics"
 	"server-api-admin/endpoints/admin/order"
 	"server-api-admin/endpoints/admin/orders"
+	passwordreset "server-api-admin/endpoints/admin/password-reset"
 	"server-api-admin/endpoints/admin/product"
 	"server-api-admin/endpoints/admin/products"
+	"server-api-admin/endpoints/admin/sessions"
 	signin "server-api-admin/endpoints/admin/sign-in"
+	"server-api-admin/endpoints/admin/staff"
+	staffdiscount "server-api-admin/endpoints/admin/staff-discount"
+	twofactor "server-api-admin/endpoints/admin/two-factor"
+	"server-api-admin/endpoints/admin/vouchers"
 )
 
 func Listen() {
 	addproduct.Listen()
+	apitokens.Listen()
+	auditlog.Listen()
+	csrftoken.Listen()
 	editproduct.Listen()
 	firstemployee.Listen()
+	memberdiscount.Listen()
+	metrics.Listen()
 	order.Listen()
 	orders.Listen()
+	passwordreset.Listen()
 	product.Listen()
 	products.Listen()
+	sessions.Listen()
 	signin.Listen()
+	staff.Listen()
+	staffdiscount.Listen()
+	twofactor.Listen()
+	vouchers.Listen()
 }
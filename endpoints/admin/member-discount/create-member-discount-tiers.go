@@ -0,0 +1,93 @@
+package memberdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/memberdiscount"
+	"server-api-admin/util/postgresdb"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CreateTiersRequest struct {
+	// Unix milliseconds, 0 to take effect immediately
+	EffectiveFrom int64                       `json:"effectiveFrom"`
+	Tiers         []models.MemberDiscountTier `json:"tiers"`
+}
+
+// createMemberDiscountTiers replaces the tiers from a date onwards. Sets can't be backdated,
+// so the tiers that applied to past orders stay as they were.
+func createMemberDiscountTiers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req CreateTiersRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Tiers) == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != 0 {
+		if time.UnixMilli(req.EffectiveFrom).Before(effectiveFrom) {
+			http.Error(w, "Tiers can't take effect in the past", http.StatusBadRequest)
+			return
+		}
+		effectiveFrom = time.UnixMilli(req.EffectiveFrom)
+	}
+
+	// Higher thresholds must earn higher rates
+	for i, t := range req.Tiers {
+		if t.MinSpend <= 0 || t.Rate <= 0 || t.Rate >= 1 {
+			http.Error(w, "Each tier needs a positive minimum spend and a rate between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		if i > 0 && (t.MinSpend <= req.Tiers[i-1].MinSpend || t.Rate <= req.Tiers[i-1].Rate) {
+			http.Error(w, "Tiers must be in ascending order of minimum spend and rate", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	previousTiers, err := memberdiscount.FetchTiers(r.Context(), tx, effectiveFrom)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = memberdiscount.CreateTierSet(r.Context(), tx, effectiveFrom, req.Tiers, userID)
+	if err == memberdiscount.ErrTierSetExists {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "create_member_discount_tiers", "member_discount_tier", effectiveFrom.UTC().Format(time.RFC3339))
+	entry.Before = map[string]interface{}{"tiers": previousTiers}
+	entry.After = map[string]interface{}{"tiers": req.Tiers}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
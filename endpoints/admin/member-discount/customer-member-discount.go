@@ -0,0 +1,47 @@
+package memberdiscount
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/memberdiscount"
+	"server-api-admin/util/postgresdb"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CustomerRequest struct {
+	UserID string `json:"userID"`
+}
+
+func customerMemberDiscount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req CustomerRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	customer, err := memberdiscount.FetchCustomer(r.Context(), tx, req.UserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"customer": customer,
+	})
+}
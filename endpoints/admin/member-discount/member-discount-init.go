@@ -0,0 +1,35 @@
+package memberdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/memberdiscount"
+	"server-api-admin/util/postgresdb"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func memberDiscountInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	currentTiers, err := memberdiscount.FetchTiers(r.Context(), tx, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tierSets, err := memberdiscount.FetchTierSets(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"currentTiers": currentTiers,
+		"tierSets":     tierSets,
+	})
+}
@@ -0,0 +1,13 @@
+package memberdiscount
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/member-discount-init", middlewares.Middleware(memberDiscountInit, permissions.ManageDiscounts))
+	router.Router.POST("/admin/create-member-discount-tiers", middlewares.Middleware(createMemberDiscountTiers, permissions.ManageDiscounts))
+	router.Router.POST("/admin/customer-member-discount", middlewares.Middleware(customerMemberDiscount, permissions.ViewCustomers))
+}
@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/metrics", middlewares.Middleware(metrics, permissions.ViewMetrics))
+}
@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// metrics serves every published expvar, such as the session reaper counters
+func metrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	expvar.Handler().ServeHTTP(w, r)
+}
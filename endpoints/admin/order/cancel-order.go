@@ -0,0 +1,100 @@
+package order
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/orders"
+	"server-api-admin/util/postgresdb"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CancelOrderRequest struct {
+	OrderID int `json:"orderID"`
+	// Leave empty to cancel the whole order
+	Items []orders.CancelItem `json:"items"`
+	Note  string              `json:"note"`
+	// Work out the stock and coupon effects without saving anything
+	DryRun bool `json:"dryRun"`
+}
+
+func cancelOrder(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req CancelOrderRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if req.OrderID == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Note) > maxNoteLength {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	// A dry run goes through exactly the same steps and is then rolled back, so the preview can't drift from the real thing
+	cancellation, err := orders.Cancel(r.Context(), tx, req.OrderID, req.Items, userID, req.Note)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	} else if err == orders.ErrNotCancellable {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err == orders.ErrUnknownOrderItem || err == orders.ErrCancelQuantityRange {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if req.DryRun {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"cancellation": cancellation,
+			"dryRun":       true,
+		})
+		return
+	}
+
+	entry := audit.NewEntry(r, "cancel_order", "order", strconv.Itoa(req.OrderID))
+	entry.Before = map[string]interface{}{"orderStatusID": cancellation.FromStatusID}
+	entry.After = map[string]interface{}{
+		"orderStatusID":           cancellation.ToStatusID,
+		"cancelledAmount":         cancellation.CancelledAmount,
+		"restocked":               cancellation.Restocked,
+		"notRestocked":            cancellation.NotRestocked,
+		"releasedMemberCouponIDs": cancellation.ReleasedMemberCouponIDs,
+		"releasedVoucherCode":     cancellation.ReleasedVoucherCode,
+		"note":                    req.Note,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cancellation": cancellation,
+		"dryRun":       false,
+	})
+}
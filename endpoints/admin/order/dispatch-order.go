@@ -0,0 +1,183 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/delivery"
+	"server-api-admin/util/emailqueue"
+	"server-api-admin/util/orders"
+	"server-api-admin/util/postgresdb"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxBulkDispatch caps how many orders one bulk dispatch may hold, a picking batch is well under this
+const maxBulkDispatch = 200
+
+type DispatchRequest struct {
+	OrderID        int    `json:"orderID"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"trackingNumber"`
+}
+
+type BulkDispatchRequest struct {
+	Orders []DispatchRequest `json:"orders"`
+}
+
+type dispatchError struct {
+	OrderID int    `json:"orderID"`
+	Error   string `json:"error"`
+}
+
+func dispatchOrder(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req DispatchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dispatch(w, r, []DispatchRequest{req})
+}
+
+func dispatchOrders(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req BulkDispatchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Orders) > maxBulkDispatch {
+		http.Error(w, "Too many orders, the maximum is "+strconv.Itoa(maxBulkDispatch), http.StatusBadRequest)
+		return
+	}
+
+	dispatch(w, r, req.Orders)
+}
+
+// dispatch marks every order as dispatched in one transaction, so a batch either goes out as a whole or not at all.
+// The shipping emails are only queued once the transaction has committed.
+func dispatch(w http.ResponseWriter, r *http.Request, reqs []DispatchRequest) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	if len(reqs) == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	// Check every tracking number before touching the database, so staff can fix all the typos in one go
+	invalid := make([]dispatchError, 0)
+	seen := make(map[int]bool, len(reqs))
+	for i := range reqs {
+		reqs[i].TrackingNumber = delivery.NormaliseTrackingNumber(reqs[i].TrackingNumber)
+
+		switch {
+		case reqs[i].OrderID == 0 || reqs[i].Carrier == "" || reqs[i].TrackingNumber == "":
+			invalid = append(invalid, dispatchError{reqs[i].OrderID, "Missing required fields"})
+		case seen[reqs[i].OrderID]:
+			invalid = append(invalid, dispatchError{reqs[i].OrderID, "Order is listed more than once"})
+		default:
+			if err := delivery.ValidateTrackingNumber(reqs[i].Carrier, reqs[i].TrackingNumber); err != nil {
+				invalid = append(invalid, dispatchError{reqs[i].OrderID, err.Error()})
+			}
+		}
+		seen[reqs[i].OrderID] = true
+	}
+
+	if len(invalid) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"invalid": invalid,
+		})
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	dispatched := make([]orders.DispatchedOrder, 0, len(reqs))
+	for _, req := range reqs {
+		d, from, err := orders.Dispatch(r.Context(), tx, req.OrderID, req.Carrier, req.TrackingNumber, userID)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"invalid": []dispatchError{{req.OrderID, "Order not found"}},
+			})
+			return
+		} else if err == orders.ErrIllegalTransition {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"invalid": []dispatchError{{req.OrderID, err.Error()}},
+			})
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		entry := audit.NewEntry(r, "dispatch_order", "order", strconv.Itoa(req.OrderID))
+		entry.Before = map[string]interface{}{"orderStatusID": from}
+		entry.After = map[string]interface{}{
+			"orderStatusID":  orders.StatusDispatched,
+			"carrier":        req.Carrier,
+			"trackingNumber": req.TrackingNumber,
+		}
+		err = audit.Record(r.Context(), tx, entry)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		dispatched = append(dispatched, d)
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dispatchedAt := time.Now()
+	for _, d := range dispatched {
+		enqueueDispatchEmail(r.Context(), d, dispatchedAt)
+	}
+
+	orderIDs := make([]int, 0, len(dispatched))
+	for _, d := range dispatched {
+		orderIDs = append(orderIDs, d.OrderID)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dispatchedOrderIDs": orderIDs,
+		"dispatchedAt":       dispatchedAt.UnixMilli(),
+	})
+}
+
+// enqueueDispatchEmail queues the "your order has shipped" email. The order has already been dispatched,
+// so a failure is only logged.
+func enqueueDispatchEmail(ctx context.Context, d orders.DispatchedOrder, dispatchedAt time.Time) {
+	err := emailqueue.Enqueue(
+		ctx,
+		config.LowPriorityEmailQueue,
+		emailqueue.TemplateOrderDispatched,
+		d.Email,
+		map[string]interface{}{
+			"orderID":        d.OrderID,
+			"firstName":      d.FirstName,
+			"carrier":        delivery.CarrierName(d.Carrier),
+			"trackingNumber": d.TrackingNumber,
+			"trackingURL":    delivery.TrackingURL(d.Carrier, d.TrackingNumber),
+			"dispatchedAt":   dispatchedAt.UnixMilli(),
+		},
+	)
+	if err != nil {
+		log.Printf("Error enqueueing dispatch email for order %d: %v", d.OrderID, err)
+	}
+}
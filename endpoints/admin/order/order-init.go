@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"server-api-admin/util/delivery"
 	"server-api-admin/util/orders"
 	"server-api-admin/util/postgresdb"
 	"time"
@@ -40,6 +41,7 @@ type Order struct {
 	Country                        string  `json:"country"`
 	CollectionPoint                string  `json:"collectionPoint"`
 	Region                         string  `json:"region"`
+	Carrier                        string  `json:"carrier"`
 	TrackingNumber                 string  `json:"trackingNumber"`
 	DispatchDT                     int64   `json:"dispatchDT"`
 	ReceiptDT                      int64   `json:"receiptDT"`
@@ -96,6 +98,7 @@ func orderInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 				rc.country,
 				COALESCE(r.name,'') as region,
 				COALESCE(la.name,'') as collection_point,
+				COALESCE(co.carrier,''),
 				COALESCE(tracking_number,''),
 				dispatch_datetime,
 				receipt_datetime,
@@ -136,6 +139,7 @@ func orderInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		&order.Country,
 		&order.Region,
 		&order.CollectionPoint,
+		&order.Carrier,
 		&order.TrackingNumber,
 		&dispatchDT,
 		&receiptDT,
@@ -157,10 +161,26 @@ func orderInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		order.ReceiptDT = receiptDT.Time.UnixMilli()
 	}
 
+	statusHistory, err := orders.FetchStatusHistory(r.Context(), tx, order.OrderID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lineItems, err := orders.FetchLineItems(r.Context(), tx, order.OrderID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	tx.Commit()
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"orderStatuses": orderStatuses,
-		"order":         order,
+		"orderStatuses":   orderStatuses,
+		"order":           order,
+		"lineItems":       lineItems,
+		"statusHistory":   statusHistory,
+		"allowedStatuses": orders.AllowedTransitions(order.OrderStatusID),
+		"carriers":        delivery.FetchCarriers(),
 	})
 }
@@ -1,7 +1,15 @@
 package order
 
-import "server-api-admin/util/router"
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
 
 func Listen() {
-	router.Router.POST("/admin/order-init", orderInit)
+	router.Router.POST("/admin/order-init", middlewares.Middleware(orderInit, permissions.ViewOrders))
+	router.Router.POST("/admin/cancel-order", middlewares.Middleware(cancelOrder, permissions.EditOrders))
+	router.Router.POST("/admin/dispatch-order", middlewares.Middleware(dispatchOrder, permissions.EditOrders))
+	router.Router.POST("/admin/dispatch-orders", middlewares.Middleware(dispatchOrders, permissions.EditOrders))
+	router.Router.POST("/admin/transition-order-status", middlewares.Middleware(transitionOrderStatus, permissions.EditOrders))
 }
@@ -0,0 +1,96 @@
+package order
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/orders"
+	"server-api-admin/util/postgresdb"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const maxNoteLength = 500
+
+type TransitionRequest struct {
+	OrderID       int    `json:"orderID"`
+	OrderStatusID int    `json:"orderStatusID"`
+	Note          string `json:"note"`
+}
+
+func transitionOrderStatus(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req TransitionRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if req.OrderID == 0 || req.OrderStatusID == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	// Cancelling has to put the stock back and release coupons, which cancel-order takes care of
+	if req.OrderStatusID == orders.StatusCancelled {
+		http.Error(w, "Orders are cancelled through /admin/cancel-order", http.StatusBadRequest)
+		return
+	}
+
+	// Dispatching needs a carrier and tracking number, and sends the shipping email
+	if req.OrderStatusID == orders.StatusDispatched {
+		http.Error(w, "Orders are dispatched through /admin/dispatch-order", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Note) > maxNoteLength {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	from, err := orders.Transition(r.Context(), tx, req.OrderID, req.OrderStatusID, userID, req.Note)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	} else if err == orders.ErrIllegalTransition {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":           err.Error(),
+			"orderStatusID":   from,
+			"allowedStatuses": orders.AllowedTransitions(from),
+		})
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "transition_order_status", "order", strconv.Itoa(req.OrderID))
+	entry.Before = map[string]interface{}{"orderStatusID": from}
+	entry.After = map[string]interface{}{"orderStatusID": req.OrderStatusID, "note": req.Note}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orderStatusID":   req.OrderStatusID,
+		"allowedStatuses": orders.AllowedTransitions(req.OrderStatusID),
+	})
+}
@@ -2,16 +2,40 @@ package orders
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"server-api-admin/util/delivery"
 	"server-api-admin/util/orders"
 	"server-api-admin/util/postgresdb"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+const (
+	defaultLimit    = 50
+	maxLimit        = 200
+	maxSearchLength = 100
+)
+
 type OrdersInitRequest struct {
 	OrderStatus int `json:"orderStatus"`
+	// Unix milliseconds, 0 for no bound
+	From             int64  `json:"from"`
+	To               int64  `json:"to"`
+	DeliveryMethodID int    `json:"deliveryMethodID"`
+	PaymentMethodID  int    `json:"paymentMethodID"`
+	RegionID         int    `json:"regionID"`
+	Country          string `json:"country"`
+	MinAmount        int    `json:"minAmount"`
+	MaxAmount        int    `json:"maxAmount"`
+	Email            string `json:"email"`
+	Search           string `json:"search"`
+	SortBy           string `json:"sortBy"`
+	Descending       bool   `json:"descending"`
+	Cursor           string `json:"cursor"`
+	Limit            int    `json:"limit"`
 }
 
 func ordersInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -19,7 +43,13 @@ func ordersInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, page, err := validateRequest(req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -32,8 +62,11 @@ func ordersInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
-	overviewItems, err := orders.FetchOrderOverview(r.Context(), tx, req.OrderStatus)
-	if err != nil {
+	overviewItems, nextCursor, totalCount, err := orders.FetchOrderOverview(r.Context(), tx, filter, page)
+	if err == orders.ErrInvalidCursor {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -44,11 +77,88 @@ func ordersInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
+	paymentMethods, err := orders.FetchPaymentMethods(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	regions, err := delivery.FetchRegions(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	tx.Commit()
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"orderStatuses":   orderStatuses,
 		"overviewItems":   overviewItems,
+		"nextCursor":      nextCursor,
+		"totalCount":      totalCount,
 		"deliveryMethods": deliveryMethods,
+		"paymentMethods":  paymentMethods,
+		"regions":         regions,
 	})
 }
+
+// validateRequest checks the filters and turns them into the query parameters FetchOrderOverview takes
+func validateRequest(req OrdersInitRequest) (orders.OverviewFilter, orders.OverviewPage, error) {
+	filter := orders.OverviewFilter{
+		OrderStatusID:    req.OrderStatus,
+		DeliveryMethodID: req.DeliveryMethodID,
+		PaymentMethodID:  req.PaymentMethodID,
+		RegionID:         req.RegionID,
+		Country:          strings.TrimSpace(req.Country),
+		MinAmount:        req.MinAmount,
+		MaxAmount:        req.MaxAmount,
+		Email:            strings.TrimSpace(req.Email),
+		Search:           strings.TrimSpace(req.Search),
+	}
+	page := orders.OverviewPage{
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		After:      req.Cursor,
+		Limit:      req.Limit,
+	}
+
+	if page.SortBy == "" {
+		page.SortBy = orders.SortByStatus
+	}
+	if !orders.ValidSortBy(page.SortBy) {
+		return filter, page, errors.New("unknown sortBy")
+	}
+	if page.Limit <= 0 || page.Limit > maxLimit {
+		page.Limit = defaultLimit
+	}
+
+	if req.OrderStatus < 0 || req.DeliveryMethodID < 0 || req.PaymentMethodID < 0 || req.RegionID < 0 {
+		return filter, page, errors.New("IDs can't be negative")
+	}
+
+	if req.From < 0 || req.To < 0 {
+		return filter, page, errors.New("dates can't be negative")
+	}
+	if req.From != 0 {
+		filter.From = time.UnixMilli(req.From)
+	}
+	if req.To != 0 {
+		filter.To = time.UnixMilli(req.To)
+	}
+	if req.From != 0 && req.To != 0 && req.From >= req.To {
+		return filter, page, errors.New("from must be before to")
+	}
+
+	if req.MinAmount < 0 || req.MaxAmount < 0 {
+		return filter, page, errors.New("amounts can't be negative")
+	}
+	if req.MaxAmount != 0 && req.MinAmount > req.MaxAmount {
+		return filter, page, errors.New("minAmount can't be more than maxAmount")
+	}
+
+	if len(filter.Email) > maxSearchLength || len(filter.Search) > maxSearchLength || len(filter.Country) > maxSearchLength {
+		return filter, page, errors.New("search terms are too long")
+	}
+
+	return filter, page, nil
+}
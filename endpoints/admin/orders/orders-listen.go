@@ -2,9 +2,10 @@ package orders
 
 import (
 	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/router"
 )
 
 func Listen() {
-	router.Router.POST("/admin/orders-init", middlewares.Middleware(ordersInit))
+	router.Router.POST("/admin/orders-init", middlewares.Middleware(ordersInit, permissions.ViewOrders))
 }
@@ -0,0 +1,140 @@
+package passwordreset
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/clientip"
+	"server-api-admin/util/emailqueue"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/tokens"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	resetTokenLifetime = 30 * time.Minute
+	requestWindow      = time.Hour
+	maxEmailRequests   = 3
+	maxIPRequests      = 20
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// forgotPassword always answers the same way, whether or not the email belongs to a staff member
+func forgotPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	// Stop the endpoint being used to flood an inbox
+	emailRequests, err := ratelimit.Add(r.Context(), config.RedisKeyPrefixPasswordReset+"email:"+email, requestWindow)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ipRequests, err := ratelimit.Add(r.Context(), config.RedisKeyPrefixPasswordReset+"ip:"+clientip.FromRequest(r), requestWindow)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if emailRequests > maxEmailRequests || ipRequests > maxIPRequests {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	var userID, userEmail string
+	err = tx.QueryRowContext(
+		r.Context(),
+		`SELECT user_id, email FROM "user" WHERE LOWER(email) = $1 AND email_verified AND staff_permission > 0`,
+		email,
+	).Scan(&userID, &userEmail)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusOK)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Only the latest link works
+	_, err = tx.ExecContext(
+		r.Context(),
+		"UPDATE staff_password_reset SET used_at = NOW() WHERE user_id = $1::uuid AND used_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token, tokenHash, err := tokens.Generate()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(resetTokenLifetime)
+	_, err = tx.ExecContext(
+		r.Context(),
+		"INSERT INTO staff_password_reset (user_id, token_hash, expires_at) VALUES ($1::uuid, $2, $3)",
+		userID,
+		tokenHash,
+		expiresAt,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "request_password_reset", "user", userID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = emailqueue.Enqueue(
+		r.Context(),
+		config.HighPriorityEmailQueue,
+		emailqueue.TemplateStaffPasswordReset,
+		userEmail,
+		map[string]interface{}{
+			"resetURL":  config.AdminSiteURL + "/reset-password?token=" + url.QueryEscape(token),
+			"expiresAt": expiresAt.UnixMilli(),
+		},
+	)
+	if err != nil {
+		// Answering differently would reveal that the email belongs to staff
+		log.Printf("Error enqueueing password reset email: %v", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
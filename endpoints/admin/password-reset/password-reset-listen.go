@@ -0,0 +1,11 @@
+package passwordreset
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.POST("/admin/forgot-password", middlewares.Middleware(forgotPassword))
+	router.Router.POST("/admin/reset-password", middlewares.Middleware(resetPassword))
+}
@@ -0,0 +1,109 @@
+package passwordreset
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/password"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+	"server-api-admin/util/tokens"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func resetPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !password.VerifyPassword(req.Password) {
+		http.Error(w, "Invalid password", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	var resetID int
+	var userID, email string
+	err = tx.QueryRowContext(
+		r.Context(),
+		`
+			SELECT spr.reset_id, u.user_id, u.email
+			FROM staff_password_reset spr
+			JOIN "user" u ON u.user_id = spr.user_id
+			WHERE spr.token_hash = $1 AND spr.used_at IS NULL AND spr.expires_at > NOW() AND u.staff_permission > 0
+			FOR UPDATE OF spr
+		`,
+		tokens.Hash(req.Token),
+	).Scan(&resetID, &userID, &email)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Reset link is invalid or has expired", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	completeHash, encodedSalt, err := password.GeneratePasswordHash(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to generate password hash", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(
+		r.Context(),
+		`UPDATE "user" SET password_hash = $1, salt = $2 WHERE user_id = $3::uuid`,
+		completeHash,
+		encodedSalt,
+		userID,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), "UPDATE staff_password_reset SET used_at = NOW() WHERE reset_id = $1", resetID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Anyone holding an old session has to sign in with the new password
+	_, err = sessions.RevokeUserSessions(r.Context(), tx, userID, "")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "reset_password", "user", userID)
+	entry.ActorID = userID
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.InvalidateUser(r.Context(), userID)
+
+	ratelimit.ResetSignInFailures(r.Context(), email)
+
+	w.WriteHeader(http.StatusOK)
+}
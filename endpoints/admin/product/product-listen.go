@@ -2,9 +2,10 @@ package product
 
 import (
 	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/router"
 )
 
 func Listen() {
-	router.Router.GET("/admin/product/:product-id", middlewares.Middleware(productInit))
+	router.Router.GET("/admin/product/:product-id", middlewares.Middleware(productInit, permissions.ViewProducts))
 }
@@ -2,9 +2,10 @@ package products
 
 import (
 	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/router"
 )
 
 func Listen() {
-	router.Router.GET("/admin/products", middlewares.Middleware(products))
+	router.Router.GET("/admin/products", middlewares.Middleware(products, permissions.ViewProducts))
 }
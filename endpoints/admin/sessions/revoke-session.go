@@ -0,0 +1,102 @@
+package sessions
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RevokeSessionRequest struct {
+	Handle string `json:"handle"`
+}
+
+func revokeSession(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	currentSessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	var req RevokeSessionRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	sessionID, err := sessions.FindSessionByHandle(r.Context(), tx, userID, req.Handle)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID == "" {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	err = sessions.RevokeSession(r.Context(), tx, sessionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "revoke_session", "staff_session", req.Handle))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.Invalidate(r.Context(), userID, sessionID)
+
+	if sessionID == currentSessionID {
+		middlewares.EndSession(w)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func revokeOtherSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	currentSessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	revoked, err := sessions.RevokeUserSessions(r.Context(), tx, userID, currentSessionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "revoke_other_sessions", "user", userID)
+	entry.After = map[string]interface{}{"revoked": revoked}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.InvalidateUser(r.Context(), userID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,84 @@
+package sessions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+	"server-api-admin/util/staff"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RevokeUserSessionsRequest struct {
+	UserID string `json:"userID"`
+}
+
+func revokeUserSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	actorPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	var req RevokeUserSessionsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	targetPermission, err := staff.FetchStaffPermission(r.Context(), tx, req.UserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !actorPermission.Has(targetPermission) {
+		http.Error(w, "Insufficient permission", http.StatusForbidden)
+		return
+	}
+
+	revoked, err := sessions.RevokeUserSessions(r.Context(), tx, req.UserID, "")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "revoke_user_sessions", "user", req.UserID)
+	entry.After = map[string]interface{}{"revoked": revoked}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.InvalidateUser(r.Context(), req.UserID)
+
+	if req.UserID == userID {
+		middlewares.EndSession(w)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,31 @@
+package sessions
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessions"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func sessionsInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	sessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	staffSessions, err := sessions.FetchUserSessions(r.Context(), tx, userID, sessionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": staffSessions,
+	})
+}
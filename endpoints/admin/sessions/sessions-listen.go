@@ -0,0 +1,15 @@
+package sessions
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.POST("/admin/sign-out", middlewares.Middleware(signOut, permissions.SignedIn))
+	router.Router.GET("/admin/sessions-init", middlewares.Middleware(sessionsInit, permissions.SignedIn))
+	router.Router.POST("/admin/revoke-session", middlewares.Middleware(revokeSession, permissions.SignedIn))
+	router.Router.POST("/admin/revoke-other-sessions", middlewares.Middleware(revokeOtherSessions, permissions.SignedIn))
+	router.Router.POST("/admin/revoke-user-sessions", middlewares.Middleware(revokeUserSessions, permissions.ManageStaff))
+}
@@ -0,0 +1,43 @@
+package sessions
+
+import (
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func signOut(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	sessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	err := sessions.RevokeSession(r.Context(), tx, sessionID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "sign_out", "staff_session", sessions.Handle(sessionID)))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.Invalidate(r.Context(), userID, sessionID)
+
+	middlewares.EndSession(w)
+	w.WriteHeader(http.StatusOK)
+}
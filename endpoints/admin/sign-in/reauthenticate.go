@@ -0,0 +1,126 @@
+package signin
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/clientip"
+	"server-api-admin/util/password"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+	"server-api-admin/util/twofactor"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// reauthenticate stamps the current session after the password or a TOTP code has been entered again,
+// unlocking routes wrapped in middlewares.RequireRecentAuth for config.ReauthenticationWindow
+func reauthenticate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	sessionID := r.Context().Value(config.SessionIDKey).(string)
+
+	var req ReauthenticateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" && req.Code == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	var email, passwordHash, salt string
+	err = tx.QueryRowContext(
+		r.Context(),
+		`SELECT email, password_hash, salt FROM "user" WHERE user_id = $1::uuid`,
+		userID,
+	).Scan(&email, &passwordHash, &salt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Failures count towards the same limits as sign-in, so a stolen session can't be used to guess the password
+	ip := clientip.FromRequest(r)
+	allowed, delay, err := ratelimit.SignInAllowed(r.Context(), email, ip)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	if !allowed {
+		http.Error(w, "Too many attempts, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var valid bool
+	method := "password"
+	if req.Code != "" {
+		method = "code"
+		valid, err = twofactor.VerifyCode(r.Context(), tx, userID, req.Code, false)
+	} else {
+		valid, err = password.ComparePasswordWithHash(req.Password, passwordHash, salt)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		recordSignInFailure(r, email, ip)
+		http.Error(w, "Password or code is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	ratelimit.ResetSignInFailures(r.Context(), email)
+
+	var reauthenticatedAt time.Time
+	err = tx.QueryRowContext(
+		r.Context(),
+		`UPDATE staff_sessions SET reauthenticated_at = NOW() WHERE session_id = $1 RETURNING reauthenticated_at`,
+		sessionID,
+	).Scan(&reauthenticatedAt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "reauthenticate", "staff_session", sessions.Handle(sessionID))
+	entry.After = map[string]interface{}{"method": method}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.Invalidate(r.Context(), userID, sessionID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reauthenticatedUntil": reauthenticatedAt.Add(config.ReauthenticationWindow).UnixMilli(),
+	})
+}
@@ -2,10 +2,14 @@ package signin
 
 import (
 	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/router"
 )
 
 func Listen() {
 	router.Router.GET("/admin/sign-in-page-init", middlewares.Middleware(signInPageInit))
 	router.Router.POST("/admin/sign-in", middlewares.Middleware(signIn))
+	router.Router.POST("/admin/sign-in-two-factor", middlewares.Middleware(signInTwoFactor))
+	router.Router.POST("/admin/sign-in-two-factor-enroll", middlewares.Middleware(signInTwoFactorEnroll))
+	router.Router.POST("/admin/reauthenticate", middlewares.Middleware(reauthenticate, permissions.SignedIn))
 }
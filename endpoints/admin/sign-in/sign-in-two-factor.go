@@ -0,0 +1,194 @@
+package signin
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/clientip"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/staff"
+	"server-api-admin/util/twofactor"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type TwoFactorRequest struct {
+	PendingToken string `json:"pendingToken"`
+	Code         string `json:"code"`
+}
+
+// signInTwoFactor completes a sign-in with a TOTP or recovery code. Staff who are still enrolling
+// confirm their new secret here, and receive their recovery codes in the response.
+func signInTwoFactor(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req TwoFactorRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, rememberDevice, err := twofactor.FetchPendingSignIn(r.Context(), req.PendingToken)
+	if err == twofactor.ErrPendingSignInNotFound {
+		http.Error(w, "Sign-in has expired, please start again", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	staffPermission, err := staff.FetchStaffPermission(r.Context(), tx, userID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Deactivated while the sign-in was pending
+	if staffPermission <= 0 {
+		http.Error(w, "Sign-in has expired, please start again", http.StatusUnauthorized)
+		return
+	}
+
+	// Wrong codes count towards the same limits as wrong passwords, otherwise signing in again for a fresh
+	// pending token would allow unlimited guesses
+	email, err := staff.FetchEmail(r.Context(), tx, userID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ip := clientip.FromRequest(r)
+	allowed, delay, err := ratelimit.SignInAllowed(r.Context(), email, ip)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	if !allowed {
+		twofactor.DeletePendingSignIn(r.Context(), req.PendingToken)
+		http.Error(w, "Sign-in has expired, please start again", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, err := twofactor.IsEnabled(r.Context(), tx, userID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	valid, err := twofactor.VerifyCode(r.Context(), tx, userID, req.Code, true)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		twofactor.RecordFailedAttempt(r.Context(), req.PendingToken)
+		recordSignInFailure(r, email, ip)
+		http.Error(w, "Code is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	var recoveryCodes []string
+	if !enabled {
+		recoveryCodes, err = twofactor.Enable(r.Context(), tx, userID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		entry := audit.NewEntry(r, "enable_two_factor", "user", userID)
+		entry.ActorID = userID
+		err = audit.Record(r.Context(), tx, entry)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	csrfToken, err := startSession(w, r, tx, userID, staffPermission, rememberDevice)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	twofactor.DeletePendingSignIn(r.Context(), req.PendingToken)
+	ratelimit.ResetSignInFailures(r.Context(), email)
+
+	response := map[string]interface{}{
+		"csrfToken": csrfToken,
+	}
+	if recoveryCodes != nil {
+		response["recoveryCodes"] = recoveryCodes
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+type TwoFactorEnrollRequest struct {
+	PendingToken string `json:"pendingToken"`
+}
+
+// signInTwoFactorEnroll lets staff who must use two-factor authentication enrol before their first session
+func signInTwoFactorEnroll(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req TwoFactorEnrollRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _, err := twofactor.FetchPendingSignIn(r.Context(), req.PendingToken)
+	if err == twofactor.ErrPendingSignInNotFound {
+		http.Error(w, "Sign-in has expired, please start again", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	secret, uri, err := twofactor.BeginEnrollment(r.Context(), tx, userID)
+	if err == twofactor.ErrAlreadyEnabled {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "begin_two_factor_enrollment", "user", userID)
+	entry.ActorID = userID
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
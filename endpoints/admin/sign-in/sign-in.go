@@ -2,19 +2,34 @@ package signin
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"server-api-admin/config"
-	"server-api-admin/util/middlewares"
+	"server-api-admin/util/clientip"
 	"server-api-admin/util/password"
+	"server-api-admin/util/permissions"
 	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/twofactor"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// Every failed or refused attempt gets the same response, so a lockout can't be told apart from a wrong password
+const signInFailedMessage = "Email or password is incorrect, or you are not staff"
+
 type Request struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	RememberDevice bool   `json:"rememberDevice"`
+}
+
+// recordSignInFailure counts a failed attempt, logging rather than failing the request if the count can't be kept
+func recordSignInFailure(r *http.Request, email, ip string) {
+	if err := ratelimit.RecordSignInFailure(r.Context(), email, ip); err != nil {
+		log.Printf("Error recording sign-in failure: %v", err)
+	}
 }
 
 func signIn(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -31,51 +46,111 @@ func signIn(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
+	ip := clientip.FromRequest(r)
+	allowed, delay, err := ratelimit.SignInAllowed(r.Context(), req.Email, ip)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Slow down repeated failures before doing any password hashing
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	if !allowed {
+		password.WaitComparison(r.Context())
+		http.Error(w, signInFailedMessage, http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
 	var passwordHash, salt string
-	err = postgresdb.DB.QueryRowContext(
+	var staffPermission permissions.Permission
+	err = tx.QueryRowContext(
 		r.Context(),
-		`SELECT user_id, password_hash, salt FROM "user" WHERE email = $1 AND email_verified AND staff_permission > 0`,
+		`SELECT user_id, password_hash, salt, staff_permission FROM "user" WHERE email = $1 AND email_verified AND staff_permission > 0`,
 		req.Email,
-	).Scan(&userID, &passwordHash, &salt)
+	).Scan(&userID, &passwordHash, &salt, &staffPermission)
 	if err != nil {
-		http.Error(w, "Email or password is incorrect", http.StatusBadRequest)
+		password.SimulateComparison(req.Password)
+		recordSignInFailure(r, req.Email, ip)
+		http.Error(w, signInFailedMessage, http.StatusBadRequest)
 		return
 	}
 
 	match, err := password.ComparePasswordWithHash(req.Password, passwordHash, salt)
 	if err != nil || !match {
-		http.Error(w, "Email or password is incorrect, or you are not staff", http.StatusBadRequest)
+		recordSignInFailure(r, req.Email, ip)
+		http.Error(w, signInFailedMessage, http.StatusBadRequest)
 		return
 	}
 
-	var sessionID string
-	var expiresAt time.Time
-	err = postgresdb.DB.QueryRowContext(
-		r.Context(),
-		"INSERT INTO staff_sessions (user_id) VALUES ($1::uuid) RETURNING session_id, expires_at",
-		userID,
-	).Scan(&sessionID, &expiresAt)
+	// Bring the stored hash up to the current parameters and pepper while the password is at hand
+	if password.NeedsRehash(passwordHash) {
+		completeHash, encodedSalt, err := password.GeneratePasswordHash(req.Password)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.ExecContext(
+			r.Context(),
+			`UPDATE "user" SET password_hash = $1, salt = $2 WHERE user_id = $3::uuid AND password_hash = $4`,
+			completeHash,
+			encodedSalt,
+			userID,
+			passwordHash,
+		)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	twoFactorEnabled, err := twofactor.IsEnabled(r.Context(), tx, userID)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	encryptedSessionID, err := middlewares.EncryptSessionID(sessionID)
+	// The session is only created once the second factor has been checked
+	if twoFactorEnabled || staffPermission.Has(permissions.Require2FA) {
+		tx.Commit()
+
+		pendingToken, err := twofactor.CreatePendingSignIn(r.Context(), userID, req.RememberDevice)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"twoFactorRequired":  true,
+			"enrollmentRequired": !twoFactorEnabled,
+			"pendingToken":       pendingToken,
+		})
+		return
+	}
+
+	csrfToken, err := startSession(w, r, tx, userID, staffPermission, req.RememberDevice)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Set the session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sessionID",
-		Value:    encryptedSessionID,
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Path:     "/",
-		// Secure:   true, // Set to true if using HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Failures are only forgotten once every factor has been checked
+	ratelimit.ResetSignInFailures(r.Context(), req.Email)
 
-	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"csrfToken": csrfToken,
+	})
 }
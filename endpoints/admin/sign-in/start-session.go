@@ -0,0 +1,78 @@
+package signin
+
+import (
+	"database/sql"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/clientip"
+	"server-api-admin/util/csrf"
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/sessionpolicy"
+	"server-api-admin/util/sessions"
+	"time"
+)
+
+const maxUserAgentLength = 512
+
+// startSession creates a staff session for the user under their session policy within tx, records the sign-in
+// and sets the session cookie. It returns a CSRF token for the new session. The caller commits tx.
+func startSession(w http.ResponseWriter, r *http.Request, tx *sql.Tx, userID string, staffPermission permissions.Permission, rememberDevice bool) (string, error) {
+	idleTimeout := sessionpolicy.For(staffPermission).Idle(rememberDevice)
+
+	var sessionID string
+	var expiresAt time.Time
+	err := tx.QueryRowContext(
+		r.Context(),
+		`
+			INSERT INTO staff_sessions (user_id, user_agent, ip_address, created_at, remember_device, expires_at, reauthenticated_at)
+			VALUES ($1::uuid, $2, $3, NOW(), $4, $5, NOW())
+			RETURNING session_id, expires_at
+		`,
+		userID,
+		truncate(r.UserAgent(), maxUserAgentLength),
+		clientip.FromRequest(r),
+		rememberDevice,
+		time.Now().Add(idleTimeout),
+	).Scan(&sessionID, &expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	entry := audit.NewEntry(r, "sign_in", "staff_session", sessions.Handle(sessionID))
+	entry.ActorID = userID
+	entry.After = map[string]interface{}{
+		"rememberDevice": rememberDevice,
+		"expiresAt":      expiresAt.UnixMilli(),
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	encryptedSessionID, err := middlewares.EncryptSessionID(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	// Set the session cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "sessionID",
+		Value:    encryptedSessionID,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Path:     "/",
+		// Secure:   true, // Set to true if using HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	// Tokens issued before signing in were bound to the anonymous ID
+	return csrf.Generate(csrf.Binding(r, sessionID))
+}
+
+func truncate(s string, maxLength int) string {
+	if len(s) > maxLength {
+		return s[:maxLength]
+	}
+	return s
+}
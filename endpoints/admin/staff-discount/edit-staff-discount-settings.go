@@ -0,0 +1,60 @@
+package staffdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/staffdiscount"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func editStaffDiscountSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req models.StaffDiscountSettings
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Rate < 0 || req.Rate >= 1 || req.MonthlyQuota < 0 {
+		http.Error(w, "Rate must be at least 0 and below 1, and the quota can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	previous, err := staffdiscount.FetchSettings(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = staffdiscount.SaveSettings(r.Context(), tx, req, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "edit_staff_discount_settings", "setting", "staff_discount")
+	entry.Before = previous
+	entry.After = req
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,38 @@
+package staffdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/staffdiscount"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// staffDiscountInit returns the discount settings and each staff member's usage this month
+func staffDiscountInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	settings, err := staffdiscount.FetchSettings(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	from, to := staffdiscount.MonthBounds(time.Now())
+	usage, err := staffdiscount.FetchUsage(r.Context(), tx, from, to, settings.MonthlyQuota)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings":   settings,
+		"usage":      usage,
+		"monthStart": from.UnixMilli(),
+	})
+}
@@ -0,0 +1,15 @@
+package staffdiscount
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/staff-discount-init", middlewares.Middleware(staffDiscountInit, permissions.ManageDiscounts))
+	router.Router.POST("/admin/edit-staff-discount-settings", middlewares.Middleware(editStaffDiscountSettings, permissions.ManageDiscounts))
+	router.Router.POST("/admin/staff-discount-report", middlewares.Middleware(staffDiscountReport, permissions.ManageDiscounts))
+	// Called by the storefront with an API token before it applies the discount
+	router.Router.POST("/admin/validate-staff-discount", middlewares.Middleware(validateStaffDiscount, permissions.ViewCustomers))
+}
@@ -0,0 +1,63 @@
+package staffdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/staffdiscount"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ReportRequest struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+}
+
+// staffDiscountReport returns the cost of the staff discount for a month, in total and per staff member
+func staffDiscountReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req ReportRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Year == 0 || req.Month < 1 || req.Month > 12 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	settings, err := staffdiscount.FetchSettings(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	from, to := staffdiscount.MonthBounds(time.Date(req.Year, time.Month(req.Month), 1, 0, 0, 0, 0, time.UTC))
+	usage, err := staffdiscount.FetchUsage(r.Context(), tx, from, to, settings.MonthlyQuota)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	var totalOrders, totalItems, totalDiscount int
+	for _, u := range usage {
+		totalOrders += u.Orders
+		totalItems += u.Items
+		totalDiscount += u.DiscountAmount
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"usage":         usage,
+		"totalOrders":   totalOrders,
+		"totalItems":    totalItems,
+		"totalDiscount": totalDiscount,
+	})
+}
@@ -0,0 +1,45 @@
+package staffdiscount
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/staffdiscount"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ValidateRequest struct {
+	UserID    string `json:"userID"`
+	ItemCount int    `json:"itemCount"`
+}
+
+// validateStaffDiscount tells the storefront whether a basket may be bought at the staff discount,
+// and at what rate. Nothing is reserved, the quota is taken up once the order is placed.
+func validateStaffDiscount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req ValidateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" || req.ItemCount <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	validation, err := staffdiscount.Validate(r.Context(), tx, req.UserID, req.ItemCount, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(validation)
+}
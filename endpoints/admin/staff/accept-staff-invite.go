@@ -0,0 +1,140 @@
+package staff
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/password"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/tokens"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type AcceptStaffInviteRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func acceptStaffInvite(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req AcceptStaffInviteRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	// Lock the invite so that it can only be redeemed once
+	var inviteID int
+	var email string
+	var staffPermission int64
+	err = tx.QueryRowContext(
+		r.Context(),
+		`
+			SELECT invite_id, email, staff_permission
+			FROM staff_invite
+			WHERE token_hash = $1 AND redeemed_at IS NULL AND expires_at > NOW()
+			FOR UPDATE
+		`,
+		tokens.Hash(req.Token),
+	).Scan(&inviteID, &email, &staffPermission)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invite is invalid or has expired", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// An existing account is promoted, but only after its owner proves it is theirs with their own password.
+	// Its credentials are never replaced, otherwise redeeming an invite would be a way to take over any account.
+	var userID, passwordHash, salt string
+	err = tx.QueryRowContext(
+		r.Context(),
+		`SELECT user_id, password_hash, salt FROM "user" WHERE email = $1 FOR UPDATE`,
+		email,
+	).Scan(&userID, &passwordHash, &salt)
+	if err == nil {
+		match, err := password.ComparePasswordWithHash(req.Password, passwordHash, salt)
+		if err != nil || !match {
+			http.Error(w, "Sign in with the password of your existing account to accept the invite", http.StatusUnauthorized)
+			return
+		}
+
+		_, err = tx.ExecContext(
+			r.Context(),
+			`UPDATE "user" SET staff_permission = $2, email_verified = TRUE WHERE user_id = $1::uuid`,
+			userID,
+			staffPermission,
+		)
+		if err != nil {
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+	} else if err == sql.ErrNoRows {
+		if !password.VerifyPassword(req.Password) {
+			http.Error(w, "Invalid password", http.StatusBadRequest)
+			return
+		}
+
+		completeHash, encodedSalt, err := password.GeneratePasswordHash(req.Password)
+		if err != nil {
+			http.Error(w, "Failed to generate password hash", http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.QueryRowContext(
+			r.Context(),
+			`INSERT INTO "user" (email, password_hash, salt, staff_permission, is_newsletter_subscribed, email_verified) VALUES ($1, $2, $3, $4, FALSE, TRUE) RETURNING user_id`,
+			email,
+			completeHash,
+			encodedSalt,
+			staffPermission,
+		).Scan(&userID)
+		if err != nil {
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(
+		r.Context(),
+		"UPDATE staff_invite SET redeemed_at = NOW(), redeemed_by = $1::uuid WHERE invite_id = $2",
+		userID,
+		inviteID,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "accept_staff_invite", "user", userID)
+	entry.ActorID = userID
+	entry.After = map[string]interface{}{
+		"inviteID":        inviteID,
+		"staffPermission": staffPermission,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	// A promoted account may have been staff before
+	sessioncache.InvalidateUser(r.Context(), userID)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,100 @@
+package staff
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/apitokens"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessions"
+	"server-api-admin/util/staff"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeactivateStaffRequest struct {
+	UserID string `json:"userID"`
+}
+
+func deactivateStaff(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	actorPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	var req DeactivateStaffRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == userID {
+		http.Error(w, "You cannot deactivate your own account", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	currentPermission, err := staff.FetchStaffPermission(r.Context(), tx, req.UserID)
+	if err == sql.ErrNoRows || currentPermission <= 0 {
+		http.Error(w, "Staff member not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !actorPermission.Has(currentPermission) {
+		http.Error(w, "Insufficient permission", http.StatusForbidden)
+		return
+	}
+
+	_, err = tx.ExecContext(
+		r.Context(),
+		`UPDATE "user" SET staff_permission = 0 WHERE user_id = $1::uuid`,
+		req.UserID,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = sessions.RevokeUserSessions(r.Context(), tx, req.UserID, "")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = apitokens.RevokeUserTokens(r.Context(), tx, req.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "deactivate_staff", "user", req.UserID)
+	entry.Before = map[string]interface{}{"staffPermission": currentPermission}
+	entry.After = map[string]interface{}{"staffPermission": 0}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessioncache.InvalidateUser(r.Context(), req.UserID)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,85 @@
+package staff
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/staff"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type EditStaffPermissionRequest struct {
+	UserID          string                 `json:"userID"`
+	StaffPermission permissions.Permission `json:"staffPermission"`
+}
+
+func editStaffPermission(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	actorPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	var req EditStaffPermissionRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Removing every bit is done through deactivate-staff so that sessions are cleared too
+	if req.UserID == "" || req.StaffPermission <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	currentPermission, err := staff.FetchStaffPermission(r.Context(), tx, req.UserID)
+	if err == sql.ErrNoRows || currentPermission <= 0 {
+		http.Error(w, "Staff member not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Staff can only change bits they hold themselves, on both sides of the change
+	if !actorPermission.Has(currentPermission) || !actorPermission.Has(req.StaffPermission) {
+		http.Error(w, "Insufficient permission", http.StatusForbidden)
+		return
+	}
+
+	_, err = tx.ExecContext(
+		r.Context(),
+		`UPDATE "user" SET staff_permission = $1 WHERE user_id = $2::uuid`,
+		req.StaffPermission,
+		req.UserID,
+	)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "edit_staff_permission", "user", req.UserID)
+	entry.Before = map[string]interface{}{"staffPermission": currentPermission}
+	entry.After = map[string]interface{}{"staffPermission": req.StaffPermission}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Cached sessions still carry the old permission
+	sessioncache.InvalidateUser(r.Context(), req.UserID)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,117 @@
+package staff
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/emailqueue"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/tokens"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const inviteLifetime = 72 * time.Hour
+
+type InviteStaffRequest struct {
+	Email           string                 `json:"email"`
+	StaffPermission permissions.Permission `json:"staffPermission"`
+}
+
+func inviteStaff(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	actorPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	var req InviteStaffRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || req.StaffPermission <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	// Staff can only hand out permission they hold themselves
+	if !actorPermission.Has(req.StaffPermission) {
+		http.Error(w, "Insufficient permission", http.StatusForbidden)
+		return
+	}
+
+	token, tokenHash, err := tokens.Generate()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	var inviteID int
+	var expiresAt time.Time
+	err = tx.QueryRowContext(
+		r.Context(),
+		`
+			INSERT INTO staff_invite (email, token_hash, staff_permission, invited_by, expires_at)
+			VALUES ($1, $2, $3, $4::uuid, $5)
+			RETURNING invite_id, expires_at
+		`,
+		req.Email,
+		tokenHash,
+		req.StaffPermission,
+		userID,
+		time.Now().Add(inviteLifetime),
+	).Scan(&inviteID, &expiresAt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "invite_staff", "staff_invite", strconv.Itoa(inviteID))
+	entry.After = map[string]interface{}{
+		"email":           req.Email,
+		"staffPermission": req.StaffPermission,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The token only ever goes to the invitee's inbox, whoever sent the invite can't redeem it
+	err = emailqueue.Enqueue(
+		r.Context(),
+		config.HighPriorityEmailQueue,
+		emailqueue.TemplateStaffInvite,
+		req.Email,
+		map[string]interface{}{
+			"acceptURL": config.AdminSiteURL + "/accept-invite?token=" + url.QueryEscape(token),
+			"expiresAt": expiresAt.UnixMilli(),
+		},
+	)
+	if err != nil {
+		log.Printf("Error enqueueing staff invite email: %v", err)
+		http.Error(w, "Failed to send invite email", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"inviteID":  inviteID,
+		"expiresAt": expiresAt.UnixMilli(),
+	})
+}
@@ -0,0 +1,45 @@
+package staff
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+	"server-api-admin/util/staff"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func staffInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	staffMembers, err := staff.FetchStaff(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	invites, err := staff.FetchPendingInvites(r.Context(), tx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	for i := range staffMembers {
+		staffMembers[i].SignInLocked, err = ratelimit.IsSignInLocked(r.Context(), staffMembers[i].Email)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"staff":       staffMembers,
+		"invites":     invites,
+		"permissions": permissions.Catalogue,
+	})
+}
@@ -0,0 +1,16 @@
+package staff
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/staff-init", middlewares.Middleware(staffInit, permissions.ManageStaff))
+	router.Router.POST("/admin/invite-staff", middlewares.Middleware(middlewares.RequireRecentAuth(inviteStaff), permissions.ManageStaff))
+	router.Router.POST("/admin/edit-staff-permission", middlewares.Middleware(middlewares.RequireRecentAuth(editStaffPermission), permissions.ManageStaff))
+	router.Router.POST("/admin/deactivate-staff", middlewares.Middleware(middlewares.RequireRecentAuth(deactivateStaff), permissions.ManageStaff))
+	router.Router.POST("/admin/unlock-sign-in", middlewares.Middleware(unlockSignIn, permissions.ManageStaff))
+	router.Router.POST("/admin/accept-staff-invite", middlewares.Middleware(acceptStaffInvite))
+}
@@ -0,0 +1,52 @@
+package staff
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/ratelimit"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type UnlockSignInRequest struct {
+	Email string `json:"email"`
+}
+
+func unlockSignIn(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req UnlockSignInRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	// The lockout only lives in Redis, the entry is committed once it has been cleared
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "unlock_sign_in", "email", req.Email))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = ratelimit.ResetSignInFailures(r.Context(), req.Email)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
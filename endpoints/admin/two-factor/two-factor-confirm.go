@@ -0,0 +1,119 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/twofactor"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+func twoFactorConfirm(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req CodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	enabled, err := twofactor.IsEnabled(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if enabled {
+		http.Error(w, twofactor.ErrAlreadyEnabled.Error(), http.StatusConflict)
+		return
+	}
+
+	valid, err := twofactor.VerifyCode(r.Context(), tx, userID, req.Code, true)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		http.Error(w, "Code is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := twofactor.Enable(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "enable_two_factor", "user", userID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func twoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req CodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	valid, err := twofactor.VerifyCode(r.Context(), tx, userID, req.Code, false)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		http.Error(w, "Code is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := twofactor.ReplaceRecoveryCodes(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "replace_recovery_codes", "user", userID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	})
+}
@@ -0,0 +1,63 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/twofactor"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func twoFactorDisable(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	staffPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	if staffPermission.Has(permissions.Require2FA) {
+		http.Error(w, "Two-factor authentication is required for your account", http.StatusForbidden)
+		return
+	}
+
+	var req CodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	valid, err := twofactor.VerifyCode(r.Context(), tx, userID, req.Code, false)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		http.Error(w, "Code is incorrect", http.StatusBadRequest)
+		return
+	}
+
+	err = twofactor.Disable(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "disable_two_factor", "user", userID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,44 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/twofactor"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func twoFactorEnroll(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	secret, uri, err := twofactor.BeginEnrollment(r.Context(), tx, userID)
+	if err == twofactor.ErrAlreadyEnabled {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = audit.Record(r.Context(), tx, audit.NewEntry(r, "begin_two_factor_enrollment", "user", userID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
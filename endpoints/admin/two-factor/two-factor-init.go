@@ -0,0 +1,40 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/twofactor"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func twoFactorInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+	staffPermission := r.Context().Value(config.StaffPermissionKey).(permissions.Permission)
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	enabled, err := twofactor.IsEnabled(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodesRemaining, err := twofactor.CountRecoveryCodes(r.Context(), tx, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":                enabled,
+		"required":               staffPermission.Has(permissions.Require2FA),
+		"recoveryCodesRemaining": recoveryCodesRemaining,
+	})
+}
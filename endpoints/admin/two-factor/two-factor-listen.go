@@ -0,0 +1,15 @@
+package twofactor
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/two-factor-init", middlewares.Middleware(twoFactorInit, permissions.SignedIn))
+	router.Router.POST("/admin/two-factor-enroll", middlewares.Middleware(twoFactorEnroll, permissions.SignedIn))
+	router.Router.POST("/admin/two-factor-confirm", middlewares.Middleware(twoFactorConfirm, permissions.SignedIn))
+	router.Router.POST("/admin/two-factor-recovery-codes", middlewares.Middleware(twoFactorRecoveryCodes, permissions.SignedIn))
+	router.Router.POST("/admin/two-factor-disable", middlewares.Middleware(twoFactorDisable, permissions.SignedIn))
+}
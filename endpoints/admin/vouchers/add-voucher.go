@@ -0,0 +1,65 @@
+package vouchers
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func addVoucher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var v models.Voucher
+	err := json.NewDecoder(r.Body).Decode(&v)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v.Code = vouchers.NormaliseCode(v.Code)
+	if v.Code == "" {
+		http.Error(w, "Code must be 3 to 32 letters, digits or dashes", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateVoucher(&v); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	v.ID, err = vouchers.Create(r.Context(), tx, v, userID)
+	if err == vouchers.ErrCodeTaken {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "add_voucher", "voucher", strconv.Itoa(v.ID))
+	entry.After = v
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id": v.ID,
+	})
+}
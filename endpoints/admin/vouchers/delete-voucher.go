@@ -0,0 +1,67 @@
+package vouchers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeleteVoucherRequest struct {
+	ID int `json:"id"`
+}
+
+func deleteVoucher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req DeleteVoucherRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ID == 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	previous, err := vouchers.FetchVoucher(r.Context(), tx, req.ID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voucher not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = vouchers.Delete(r.Context(), tx, req.ID)
+	if err == vouchers.ErrHasBeenUsed {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	entry := audit.NewEntry(r, "delete_voucher", "voucher", strconv.Itoa(req.ID))
+	entry.Before = previous
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package vouchers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/models"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func editVoucher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var v models.Voucher
+	err := json.NewDecoder(r.Body).Decode(&v)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v.Code = vouchers.NormaliseCode(v.Code)
+	if v.ID == 0 || v.Code == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateVoucher(&v); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	previous, err := vouchers.FetchVoucher(r.Context(), tx, v.ID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voucher not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Orders show the code and rate they were placed with
+	if previous.Code != v.Code || previous.Rate != v.Rate {
+		claimed, err := vouchers.IsClaimed(r.Context(), tx, v.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if claimed {
+			http.Error(w, "The code and rate can't change once an order has used the voucher", http.StatusConflict)
+			return
+		}
+	}
+
+	err = vouchers.Update(r.Context(), tx, v)
+	if err == vouchers.ErrCodeTaken {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Only the editable fields are compared
+	v.Uses = previous.Uses
+	v.CreatedAt = previous.CreatedAt
+	entry := audit.NewEntry(r, "edit_voucher", "voucher", strconv.Itoa(v.ID))
+	entry.Before = previous
+	entry.After = v
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
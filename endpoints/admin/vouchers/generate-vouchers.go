@@ -0,0 +1,109 @@
+package vouchers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"server-api-admin/util/audit"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	maxGeneratedVouchers = 1000
+	// Attempts at a free code before giving up, collisions are rare with 8 random characters
+	maxCodeAttempts = 5
+)
+
+var prefixPattern = regexp.MustCompile(`^[A-Z0-9]{0,12}$`)
+
+type GenerateVouchersRequest struct {
+	models.Voucher
+	Prefix string `json:"prefix"`
+	Count  int    `json:"count"`
+}
+
+// generateVouchers creates a batch of vouchers with random codes and the same terms, for a campaign
+func generateVouchers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := r.Context().Value(config.UserIDKey).(string)
+
+	var req GenerateVouchersRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Prefix = strings.ToUpper(strings.TrimSpace(req.Prefix))
+	req.Campaign = strings.TrimSpace(req.Campaign)
+	if req.Campaign == "" || req.Count <= 0 {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if req.Count > maxGeneratedVouchers {
+		http.Error(w, "Too many vouchers requested", http.StatusBadRequest)
+		return
+	}
+
+	if !prefixPattern.MatchString(req.Prefix) {
+		http.Error(w, "Prefix must be up to 12 letters or digits", http.StatusBadRequest)
+		return
+	}
+
+	v := req.Voucher
+	if msg := validateVoucher(&v); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	codes := make([]string, 0, req.Count)
+	for len(codes) < req.Count {
+		for attempt := 1; ; attempt++ {
+			v.Code, err = vouchers.GenerateCode(req.Prefix)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			_, err = vouchers.Create(r.Context(), tx, v, userID)
+			if err == nil {
+				break
+			} else if err != vouchers.ErrCodeTaken || attempt == maxCodeAttempts {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		codes = append(codes, v.Code)
+	}
+
+	v.Code = ""
+	entry := audit.NewEntry(r, "generate_vouchers", "voucher_campaign", req.Campaign)
+	entry.After = map[string]interface{}{
+		"voucher": v,
+		"prefix":  req.Prefix,
+		"count":   req.Count,
+	}
+	err = audit.Record(r.Context(), tx, entry)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"codes": codes,
+	})
+}
@@ -0,0 +1,31 @@
+package vouchers
+
+import (
+	"server-api-admin/models"
+	"time"
+)
+
+// validateVoucher fills in defaults and returns a message describing the first problem, or an empty string
+func validateVoucher(v *models.Voucher) string {
+	if v.Rate <= 0 || v.Rate >= 1 {
+		return "Rate must be between 0 and 1"
+	}
+
+	if v.ValidFrom == 0 {
+		v.ValidFrom = time.Now().UnixMilli()
+	}
+
+	if v.ValidUntil != 0 && v.ValidUntil <= v.ValidFrom {
+		return "Voucher must end after it starts"
+	}
+
+	if v.MaxUses < 0 || v.MaxUsesPerCustomer < 0 || v.MinSpend < 0 {
+		return "Limits and minimum spend can't be negative"
+	}
+
+	if v.MaxUses != 0 && v.MaxUsesPerCustomer > v.MaxUses {
+		return "Uses per customer can't exceed the total uses"
+	}
+
+	return ""
+}
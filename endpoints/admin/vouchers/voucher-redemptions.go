@@ -0,0 +1,46 @@
+package vouchers
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RedemptionsRequest struct {
+	VoucherID int    `json:"voucherID"`
+	Campaign  string `json:"campaign"`
+}
+
+// voucherRedemptions reports the orders that used a voucher, or each voucher of a campaign, and the discount given
+func voucherRedemptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req RedemptionsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	reports, err := vouchers.FetchRedemptions(r.Context(), tx, req.VoucherID, req.Campaign)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	var totalDiscount int
+	for _, report := range reports {
+		totalDiscount += report.TotalDiscount
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vouchers":      reports,
+		"totalDiscount": totalDiscount,
+	})
+}
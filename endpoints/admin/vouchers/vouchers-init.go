@@ -0,0 +1,27 @@
+package vouchers
+
+import (
+	"encoding/json"
+	"net/http"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/vouchers"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func vouchersInit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tx, _ := postgresdb.DB.BeginTx(r.Context(), nil)
+	defer tx.Rollback()
+
+	allVouchers, err := vouchers.FetchVouchers(r.Context(), tx, "")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx.Commit()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vouchers": allVouchers,
+	})
+}
@@ -0,0 +1,16 @@
+package vouchers
+
+import (
+	"server-api-admin/util/middlewares"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/router"
+)
+
+func Listen() {
+	router.Router.GET("/admin/vouchers-init", middlewares.Middleware(vouchersInit, permissions.ManageDiscounts))
+	router.Router.POST("/admin/add-voucher", middlewares.Middleware(addVoucher, permissions.ManageDiscounts))
+	router.Router.POST("/admin/edit-voucher", middlewares.Middleware(editVoucher, permissions.ManageDiscounts))
+	router.Router.POST("/admin/delete-voucher", middlewares.Middleware(deleteVoucher, permissions.ManageDiscounts))
+	router.Router.POST("/admin/generate-vouchers", middlewares.Middleware(generateVouchers, permissions.ManageDiscounts))
+	router.Router.POST("/admin/voucher-redemptions", middlewares.Middleware(voucherRedemptions, permissions.ManageDiscounts))
+}
@@ -12,6 +12,7 @@ import (
 	"server-api-admin/util/postgresdb"
 	"server-api-admin/util/redisclient"
 	"server-api-admin/util/router"
+	"server-api-admin/util/sessionreaper"
 )
 
 func main() {
@@ -23,6 +24,8 @@ func main() {
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start your services with the context
+	go sessionreaper.Run(ctx)
+
 	go func() {
 		log.Println("Starting services...")
 		endpoints.Listen()
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 type Specification struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -50,6 +52,43 @@ type OrderOverviewItem struct {
 	DeliveryMethodID           int   `json:"deliveryMethodID"`
 }
 
+type OrderLineItem struct {
+	OrderItemID int    `json:"orderItemID"`
+	ProductID   string `json:"productID"`
+	Name        string `json:"name"`
+	AdminImage  string `json:"adminImage"`
+	Quantity    int    `json:"quantity"`
+	// Items on the line that have since been cancelled and restocked
+	CancelledQuantity int `json:"cancelledQuantity"`
+	// Price of one item at the time of purchase
+	UnitPrice int `json:"unitPrice"`
+	// Discount taken off the whole line
+	Discount     int    `json:"discount"`
+	LocationID   int    `json:"locationID"`
+	LocationName string `json:"locationName"`
+}
+
+type RestockedItem struct {
+	OrderItemID int    `json:"orderItemID"`
+	ProductID   string `json:"productID"`
+	LocationID  int    `json:"locationID"`
+	Quantity    int    `json:"quantity"`
+}
+
+type OrderCancellation struct {
+	OrderID          int  `json:"orderID"`
+	FromStatusID     int  `json:"fromStatusID"`
+	ToStatusID       int  `json:"toStatusID"`
+	FullCancellation bool `json:"fullCancellation"`
+	// Value of the cancelled items at the price paid, after their line discounts
+	CancelledAmount int             `json:"cancelledAmount"`
+	Restocked       []RestockedItem `json:"restocked"`
+	// Items with no pick location, these have to be put away by hand
+	NotRestocked            []RestockedItem `json:"notRestocked"`
+	ReleasedMemberCouponIDs []int           `json:"releasedMemberCouponIDs"`
+	ReleasedVoucherCode     string          `json:"releasedVoucherCode"`
+}
+
 type DeliveryMethod struct {
 	ID              int    `json:"id"`
 	Name            string `json:"name"`
@@ -57,3 +96,157 @@ type DeliveryMethod struct {
 	MinSpendForFree int    `json:"minSpendForFree"`
 	RegionName      string `json:"regionName"`
 }
+
+type PaymentMethod struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Region struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+}
+
+type Carrier struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type StaffMember struct {
+	UserID          string `json:"userID"`
+	Email           string `json:"email"`
+	StaffPermission int64  `json:"staffPermission"`
+	SignInLocked    bool   `json:"signInLocked"`
+}
+
+type StaffInvite struct {
+	InviteID        int    `json:"inviteID"`
+	Email           string `json:"email"`
+	StaffPermission int64  `json:"staffPermission"`
+	InvitedBy       string `json:"invitedBy"`
+	ExpiresAt       int64  `json:"expiresAt"`
+}
+
+type StaffSession struct {
+	Handle    string `json:"handle"`
+	UserAgent string `json:"userAgent"`
+	IPAddress string `json:"ipAddress"`
+	CreatedAt int64  `json:"createdAt"`
+	ExpiresAt int64  `json:"expiresAt"`
+	Current   bool   `json:"current"`
+}
+
+type APIToken struct {
+	TokenID         string `json:"tokenID"`
+	Name            string `json:"name"`
+	StaffPermission int64  `json:"staffPermission"`
+	CreatedAt       int64  `json:"createdAt"`
+	ExpiresAt       int64  `json:"expiresAt"`
+	LastUsedAt      int64  `json:"lastUsedAt"`
+}
+
+type AuditLogEntry struct {
+	AuditID    int64           `json:"auditID"`
+	ActorID    string          `json:"actorID"`
+	ActorEmail string          `json:"actorEmail"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   string          `json:"entityID"`
+	IPAddress  string          `json:"ipAddress"`
+	RequestID  string          `json:"requestID"`
+	Changes    json.RawMessage `json:"changes"`
+	CreatedAt  int64           `json:"createdAt"`
+}
+
+type StaffDiscountSettings struct {
+	Rate         float64 `json:"rate"`
+	MonthlyQuota int     `json:"monthlyQuota"`
+}
+
+type StaffDiscountUsage struct {
+	UserID         string `json:"userID"`
+	Email          string `json:"email"`
+	Orders         int    `json:"orders"`
+	Items          int    `json:"items"`
+	ItemsRemaining int    `json:"itemsRemaining"`
+	DiscountAmount int    `json:"discountAmount"`
+}
+
+type StaffDiscountValidation struct {
+	Eligible       bool    `json:"eligible"`
+	Reason         string  `json:"reason,omitempty"`
+	Rate           float64 `json:"rate"`
+	MonthlyQuota   int     `json:"monthlyQuota"`
+	ItemsUsed      int     `json:"itemsUsed"`
+	ItemsRemaining int     `json:"itemsRemaining"`
+}
+
+type MemberDiscountTier struct {
+	Tier     int     `json:"tier"`
+	MinSpend int     `json:"minSpend"`
+	Rate     float64 `json:"rate"`
+}
+
+type MemberDiscountTierSet struct {
+	EffectiveFrom int64                `json:"effectiveFrom"`
+	CreatedBy     string               `json:"createdBy"`
+	Tiers         []MemberDiscountTier `json:"tiers"`
+}
+
+type MemberDiscountCoupon struct {
+	CouponID     int     `json:"couponID"`
+	DiscountRate float64 `json:"discountRate"`
+	CreatedAt    int64   `json:"createdAt"`
+	OrderID      int     `json:"orderID"`
+	ClaimedAt    int64   `json:"claimedAt"`
+}
+
+type CustomerMemberDiscount struct {
+	UserID           string                 `json:"userID"`
+	Email            string                 `json:"email"`
+	AccumulatedSpend int                    `json:"accumulatedSpend"`
+	Tier             int                    `json:"tier"`
+	Rate             float64                `json:"rate"`
+	NextTierMinSpend int                    `json:"nextTierMinSpend"`
+	Coupons          []MemberDiscountCoupon `json:"coupons"`
+}
+
+type Voucher struct {
+	ID                 int     `json:"id"`
+	Code               string  `json:"code"`
+	Rate               float64 `json:"rate"`
+	ValidFrom          int64   `json:"validFrom"`
+	ValidUntil         int64   `json:"validUntil"`
+	MaxUses            int     `json:"maxUses"`
+	MaxUsesPerCustomer int     `json:"maxUsesPerCustomer"`
+	MinSpend           int     `json:"minSpend"`
+	Campaign           string  `json:"campaign"`
+	Uses               int     `json:"uses"`
+	CreatedAt          int64   `json:"createdAt"`
+}
+
+type VoucherRedemption struct {
+	OrderID        int    `json:"orderID"`
+	OrderDate      int64  `json:"orderDate"`
+	OrderStatusID  int    `json:"orderStatusID"`
+	Email          string `json:"email"`
+	DiscountAmount int    `json:"discountAmount"`
+}
+
+type VoucherRedemptionReport struct {
+	VoucherID     int                 `json:"voucherID"`
+	Code          string              `json:"code"`
+	Rate          float64             `json:"rate"`
+	Campaign      string              `json:"campaign"`
+	Orders        []VoucherRedemption `json:"orders"`
+	TotalDiscount int                 `json:"totalDiscount"`
+}
+
+type OrderStatusChange struct {
+	FromStatusID int    `json:"fromStatusID"`
+	ToStatusID   int    `json:"toStatusID"`
+	ChangedBy    string `json:"changedBy"`
+	ChangedAt    int64  `json:"changedAt"`
+	Note         string `json:"note"`
+}
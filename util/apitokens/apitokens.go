@@ -0,0 +1,176 @@
+package apitokens
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"server-api-admin/models"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/tokens"
+	"strings"
+	"time"
+)
+
+// Prefix marks API tokens so they can be recognised in logs and by secret scanners
+const Prefix = "fmat_"
+
+// last_used_at is only written once per interval, so busy integrations don't update the row on every request
+const lastUsedResolution = time.Minute
+
+// tokenIDPattern matches the text form of a UUID, which is all a token ID can be
+var tokenIDPattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+
+var ErrInvalidToken = errors.New("invalid API token")
+
+// Create stores a new token for the user and returns it. Only its hash is kept, so the token can't be shown again.
+func Create(ctx context.Context, tx *sql.Tx, userID, name string, staffPermission permissions.Permission, expiresAt sql.NullTime) (string, models.APIToken, error) {
+	var apiToken models.APIToken
+
+	token, hash, err := tokens.Generate()
+	if err != nil {
+		return "", apiToken, err
+	}
+
+	var createdAt time.Time
+	err = tx.QueryRowContext(
+		ctx,
+		`
+			INSERT INTO staff_api_token (user_id, name, token_hash, staff_permission, created_at, expires_at)
+			VALUES ($1::uuid, $2, $3, $4, NOW(), $5)
+			RETURNING token_id, created_at
+		`,
+		userID,
+		name,
+		hash,
+		staffPermission,
+		expiresAt,
+	).Scan(&apiToken.TokenID, &createdAt)
+	if err != nil {
+		return "", apiToken, err
+	}
+
+	apiToken.Name = name
+	apiToken.StaffPermission = int64(staffPermission)
+	apiToken.CreatedAt = createdAt.UnixMilli()
+	if expiresAt.Valid {
+		apiToken.ExpiresAt = expiresAt.Time.UnixMilli()
+	}
+
+	return Prefix + token, apiToken, nil
+}
+
+func FetchUserTokens(ctx context.Context, tx *sql.Tx, userID string) ([]models.APIToken, error) {
+	apiTokens := make([]models.APIToken, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT token_id, name, staff_permission, created_at, expires_at, last_used_at
+			FROM staff_api_token
+			WHERE user_id = $1::uuid
+			ORDER BY created_at DESC
+		`,
+		userID,
+	)
+	if err != nil {
+		return apiTokens, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.APIToken
+		var createdAt time.Time
+		var expiresAt, lastUsedAt sql.NullTime
+		err = rows.Scan(&t.TokenID, &t.Name, &t.StaffPermission, &createdAt, &expiresAt, &lastUsedAt)
+		if err != nil {
+			return apiTokens, err
+		}
+
+		t.CreatedAt = createdAt.UnixMilli()
+		if expiresAt.Valid {
+			t.ExpiresAt = expiresAt.Time.UnixMilli()
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = lastUsedAt.Time.UnixMilli()
+		}
+		apiTokens = append(apiTokens, t)
+	}
+
+	return apiTokens, rows.Err()
+}
+
+// Revoke deletes one of the user's tokens and reports whether it existed
+func Revoke(ctx context.Context, tx *sql.Tx, userID, tokenID string) (bool, error) {
+	// Anything else can't name a token, and would make the uuid cast fail
+	if !tokenIDPattern.MatchString(tokenID) {
+		return false, nil
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM staff_api_token WHERE token_id = $1::uuid AND user_id = $2::uuid",
+		tokenID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeUserTokens deletes every token of a user, so they don't come back if the user is made staff again
+func RevokeUserTokens(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM staff_api_token WHERE user_id = $1::uuid", userID)
+	return err
+}
+
+// Authenticate returns the owner of a token and the permission it grants, which is limited to the bits
+// the owner still holds. Tokens of deactivated staff are rejected.
+func Authenticate(ctx context.Context, token string) (string, permissions.Permission, error) {
+	if !strings.HasPrefix(token, Prefix) {
+		return "", 0, ErrInvalidToken
+	}
+
+	var tokenID, userID string
+	var tokenPermission, staffPermission permissions.Permission
+	var lastUsedAt sql.NullTime
+	err := postgresdb.DB.QueryRowContext(
+		ctx,
+		`
+			SELECT t.token_id, t.user_id, t.staff_permission, u.staff_permission, t.last_used_at
+			FROM staff_api_token t
+			JOIN "user" u ON t.user_id = u.user_id
+			WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		`,
+		tokens.Hash(strings.TrimPrefix(token, Prefix)),
+	).Scan(&tokenID, &userID, &tokenPermission, &staffPermission, &lastUsedAt)
+	if err == sql.ErrNoRows {
+		return "", 0, ErrInvalidToken
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	effectivePermission := tokenPermission & staffPermission
+	if staffPermission <= 0 || effectivePermission <= 0 {
+		return "", 0, ErrInvalidToken
+	}
+
+	if !lastUsedAt.Valid || time.Since(lastUsedAt.Time) > lastUsedResolution {
+		_, err = postgresdb.DB.ExecContext(
+			ctx,
+			"UPDATE staff_api_token SET last_used_at = NOW() WHERE token_id = $1::uuid",
+			tokenID,
+		)
+		if err != nil {
+			log.Printf("Error updating API token last used time: %v", err)
+		}
+	}
+
+	return userID, effectivePermission, nil
+}
@@ -0,0 +1,138 @@
+// Package audit keeps the append-only record of changes made through the admin API.
+// Entries are only ever inserted, in the same transaction as the change they describe.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"server-api-admin/config"
+	"server-api-admin/util/clientip"
+)
+
+type Entry struct {
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	IP         string
+	RequestID  string
+	// State of the entity before and after the change, anything that marshals to a JSON object.
+	// Only the fields that differ are stored.
+	Before interface{}
+	After  interface{}
+}
+
+// Change is the stored difference of one field
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// NewEntry starts an entry for a change made by the request, filling in the actor, IP and request ID
+func NewEntry(r *http.Request, action, entityType, entityID string) Entry {
+	actorID, _ := r.Context().Value(config.UserIDKey).(string)
+	requestID, _ := r.Context().Value(config.RequestIDKey).(string)
+
+	return Entry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         clientip.FromRequest(r),
+		RequestID:  requestID,
+	}
+}
+
+// Record appends an entry to the audit log within the given transaction
+func Record(ctx context.Context, tx *sql.Tx, e Entry) error {
+	var actorID sql.NullString
+	if e.ActorID != "" {
+		actorID.Valid = true
+		actorID.String = e.ActorID
+	}
+
+	var requestID sql.NullString
+	if e.RequestID != "" {
+		requestID.Valid = true
+		requestID.String = e.RequestID
+	}
+
+	changes, err := Diff(e.Before, e.After)
+	if err != nil {
+		return err
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO audit_log (actor_id, action, entity_type, entity_id, ip_address, request_id, changes, created_at)
+			VALUES ($1::uuid, $2, $3, $4, $5, $6, $7, NOW())
+		`,
+		actorID,
+		e.Action,
+		e.EntityType,
+		e.EntityID,
+		e.IP,
+		requestID,
+		changesJSON,
+	)
+	return err
+}
+
+// Diff returns the fields whose JSON values differ between before and after.
+// Either side may be nil, for entities that were created or removed.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for field, b := range beforeFields {
+		a, ok := afterFields[field]
+		if !ok || string(a) != string(b) {
+			changes[field] = Change{Before: b, After: nullable(a, ok)}
+		}
+	}
+	for field, a := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = Change{Before: nil, After: a}
+		}
+	}
+
+	return changes, nil
+}
+
+func toFields(v interface{}) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if v == nil {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &fields)
+	return fields, err
+}
+
+func nullable(v json.RawMessage, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return v
+}
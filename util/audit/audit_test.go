@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type product struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+	Note  string `json:"note,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after interface{}
+		want          string
+	}{
+		{
+			name:   "created",
+			before: nil,
+			after:  product{Name: "Ring", Price: 100},
+			want:   `{"name":{"before":null,"after":"Ring"},"price":{"before":null,"after":100}}`,
+		},
+		{
+			name:   "removed",
+			before: product{Name: "Ring", Price: 100},
+			after:  nil,
+			want:   `{"name":{"before":"Ring","after":null},"price":{"before":100,"after":null}}`,
+		},
+		{
+			name:   "changed",
+			before: product{Name: "Ring", Price: 100},
+			after:  product{Name: "Ring", Price: 120},
+			want:   `{"price":{"before":100,"after":120}}`,
+		},
+		{
+			name:   "field added",
+			before: product{Name: "Ring", Price: 100},
+			after:  product{Name: "Ring", Price: 100, Note: "gift"},
+			want:   `{"note":{"before":null,"after":"gift"}}`,
+		},
+		{
+			name:   "field dropped",
+			before: product{Name: "Ring", Price: 100, Note: "gift"},
+			after:  product{Name: "Ring", Price: 100},
+			want:   `{"note":{"before":"gift","after":null}}`,
+		},
+		{
+			name:   "unchanged",
+			before: product{Name: "Ring", Price: 100},
+			after:  product{Name: "Ring", Price: 100},
+			want:   `{}`,
+		},
+		{
+			name:   "maps",
+			before: map[string]interface{}{"orderStatusID": 1},
+			after:  map[string]interface{}{"orderStatusID": 2},
+			want:   `{"orderStatusID":{"before":1,"after":2}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+
+			got, err := json.Marshal(changes)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Diff = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffRejectsNonObjects(t *testing.T) {
+	if _, err := Diff([]int{1}, []int{2}); err == nil {
+		t.Error("Diff of two slices succeeded")
+	}
+}
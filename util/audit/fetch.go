@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"server-api-admin/models"
+	"strings"
+	"time"
+)
+
+// Filter narrows down FetchEntries. Zero values are ignored.
+type Filter struct {
+	ActorID    string
+	EntityType string
+	EntityID   string
+	From       time.Time
+	To         time.Time
+	// Only entries older than this ID, for paging through the results
+	BeforeID int64
+	Limit    int
+}
+
+// FetchEntries returns the newest entries matching the filter
+func FetchEntries(ctx context.Context, tx *sql.Tx, f Filter) ([]models.AuditLogEntry, error) {
+	entries := make([]models.AuditLogEntry, 0)
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ActorID != "" {
+		where("a.actor_id = $%d::uuid", f.ActorID)
+	}
+	if f.EntityType != "" {
+		where("a.entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID != "" {
+		where("a.entity_id = $%d", f.EntityID)
+	}
+	if !f.From.IsZero() {
+		where("a.created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		where("a.created_at < $%d", f.To)
+	}
+	if f.BeforeID != 0 {
+		where("a.audit_id < $%d", f.BeforeID)
+	}
+
+	query := `
+		SELECT a.audit_id, COALESCE(a.actor_id::text, ''), COALESCE(u.email, ''), a.action, a.entity_type, a.entity_id,
+			COALESCE(a.ip_address, ''), COALESCE(a.request_id, ''), COALESCE(a.changes, '{}'::jsonb), a.created_at
+		FROM audit_log a
+		LEFT JOIN "user" u ON a.actor_id = u.user_id
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY a.audit_id DESC LIMIT $%d", len(args))
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return entries, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditLogEntry
+		var changes []byte
+		var createdAt time.Time
+		err = rows.Scan(&e.AuditID, &e.ActorID, &e.ActorEmail, &e.Action, &e.EntityType, &e.EntityID, &e.IPAddress, &e.RequestID, &changes, &createdAt)
+		if err != nil {
+			return entries, err
+		}
+
+		e.Changes = changes
+		e.CreatedAt = createdAt.UnixMilli()
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
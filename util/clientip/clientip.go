@@ -0,0 +1,33 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"server-api-admin/config"
+	"strings"
+)
+
+// FromRequest returns the IP address of the client that made the request.
+// X-Forwarded-For is only honoured when the API sits behind a trusted proxy. The client can put anything at the
+// start of the header, so the address is taken from the right, where each trusted proxy appends the one it saw.
+func FromRequest(r *http.Request) string {
+	if config.TrustProxyHeaders {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(header, ",")...)
+		}
+
+		if len(entries) > 0 {
+			ip := strings.TrimSpace(entries[max(len(entries)-config.TrustedProxyHops, 0)])
+			if net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
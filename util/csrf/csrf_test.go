@@ -0,0 +1,121 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server-api-admin/config"
+	"strings"
+	"testing"
+)
+
+func withSecret(t *testing.T, secret string) {
+	t.Helper()
+	previous := config.CSRFSecretKey
+	config.CSRFSecretKey = secret
+	t.Cleanup(func() { config.CSRFSecretKey = previous })
+}
+
+func TestValid(t *testing.T) {
+	withSecret(t, "test-secret")
+
+	token, err := Generate("session|abc")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	nonce, mac, _ := strings.Cut(token, ".")
+	tests := []struct {
+		name, token, binding string
+		want                 bool
+	}{
+		{"issued binding", token, "session|abc", true},
+		{"other session", token, "session|xyz", false},
+		{"anonymous binding", token, "anonymous|abc", false},
+		{"empty binding", token, "", false},
+		{"empty token", "", "session|abc", false},
+		{"no separator", nonce + mac, "session|abc", false},
+		{"other nonce", "AAAAAAAAAAAAAAAAAAAAAA." + mac, "session|abc", false},
+		{"mac not base64", nonce + ".!!!", "session|abc", false},
+		{"truncated mac", nonce + "." + mac[:len(mac)-2], "session|abc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(tt.token, tt.binding); got != tt.want {
+				t.Errorf("Valid = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidOtherSecret(t *testing.T) {
+	withSecret(t, "old-secret")
+	token, err := Generate("session|abc")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	config.CSRFSecretKey = "new-secret"
+	if Valid(token, "session|abc") {
+		t.Error("token signed with another secret was accepted")
+	}
+}
+
+func TestFailsClosedWithoutSecret(t *testing.T) {
+	withSecret(t, "test-secret")
+	token, err := Generate("session|abc")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	config.CSRFSecretKey = ""
+	if _, err := Generate("session|abc"); err != ErrNoSecretKey {
+		t.Errorf("Generate without a secret = %v, want ErrNoSecretKey", err)
+	}
+	if Valid(token, "session|abc") {
+		t.Error("Valid accepted a token without a secret configured")
+	}
+}
+
+func TestBinding(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	if got := Binding(r, ""); got != "" {
+		t.Errorf("Binding without session or cookie = %q, want empty", got)
+	}
+
+	r.AddCookie(&http.Cookie{Name: anonymousCookieName, Value: "visitor"})
+	if got := Binding(r, ""); got != "anonymous|visitor" {
+		t.Errorf("Binding with cookie = %q", got)
+	}
+
+	// The session always wins over the anonymous cookie
+	if got := Binding(r, "abc"); got != "session|abc" {
+		t.Errorf("Binding with session = %q", got)
+	}
+}
+
+func TestSetAnonymousID(t *testing.T) {
+	withSecret(t, "test-secret")
+
+	w := httptest.NewRecorder()
+	binding, err := SetAnonymousID(w)
+	if err != nil {
+		t.Fatalf("SetAnonymousID: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	if got := Binding(r, ""); got != binding {
+		t.Errorf("Binding from the issued cookie = %q, want %q", got, binding)
+	}
+
+	token, err := Generate(binding)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !Valid(token, Binding(r, "")) {
+		t.Error("token for the anonymous ID was rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package delivery
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"server-api-admin/models"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnknownCarrier        = errors.New("unknown carrier")
+	ErrInvalidTrackingNumber = errors.New("tracking number doesn't match the carrier's format")
+)
+
+type carrier struct {
+	name        string
+	pattern     *regexp.Regexp
+	trackingURL string // %s is replaced with the tracking number
+}
+
+// carriers are keyed by the code stored in customer_order.carrier
+var carriers = map[string]carrier{
+	"royal-mail": {
+		name:        "Royal Mail",
+		pattern:     regexp.MustCompile(`^([A-Z]{2}\d{9}GB|[A-Z0-9]{16})$`),
+		trackingURL: "https://www.royalmail.com/track-your-item#/tracking-results/%s",
+	},
+	"parcelforce": {
+		name:        "Parcelforce",
+		pattern:     regexp.MustCompile(`^([A-Z]{2}\d{7}|[A-Z]{2}\d{9}GB)$`),
+		trackingURL: "https://www.parcelforce.com/track-trace?trackNumber=%s",
+	},
+	"dpd": {
+		name:        "DPD",
+		pattern:     regexp.MustCompile(`^\d{14}$`),
+		trackingURL: "https://track.dpd.co.uk/parcels/%s",
+	},
+	"evri": {
+		name:        "Evri",
+		pattern:     regexp.MustCompile(`^[A-Z0-9]{16}$`),
+		trackingURL: "https://www.evri.com/track/parcel/%s",
+	},
+	"dhl": {
+		name:        "DHL",
+		pattern:     regexp.MustCompile(`^\d{10}$`),
+		trackingURL: "https://www.dhl.com/gb-en/home/tracking.html?tracking-id=%s",
+	},
+	"ups": {
+		name:        "UPS",
+		pattern:     regexp.MustCompile(`^1Z[A-Z0-9]{16}$`),
+		trackingURL: "https://www.ups.com/track?tracknum=%s",
+	},
+}
+
+// NormaliseTrackingNumber strips the spaces and dashes staff often copy from labels and upper-cases the rest
+func NormaliseTrackingNumber(trackingNumber string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(trackingNumber))
+}
+
+// ValidateTrackingNumber checks a normalised tracking number against the carrier's format
+func ValidateTrackingNumber(carrierCode, trackingNumber string) error {
+	c, ok := carriers[carrierCode]
+	if !ok {
+		return ErrUnknownCarrier
+	}
+
+	if !c.pattern.MatchString(trackingNumber) {
+		return ErrInvalidTrackingNumber
+	}
+
+	return nil
+}
+
+// CarrierName returns the display name of a carrier, or the code itself when it isn't known
+func CarrierName(carrierCode string) string {
+	if c, ok := carriers[carrierCode]; ok {
+		return c.name
+	}
+	return carrierCode
+}
+
+// TrackingURL returns the carrier's public tracking page for a parcel
+func TrackingURL(carrierCode, trackingNumber string) string {
+	c, ok := carriers[carrierCode]
+	if !ok {
+		return ""
+	}
+	return strings.Replace(c.trackingURL, "%s", url.QueryEscape(trackingNumber), 1)
+}
+
+// FetchCarriers lists the carriers orders can be dispatched with, sorted by name
+func FetchCarriers() []models.Carrier {
+	list := make([]models.Carrier, 0, len(carriers))
+	for code, c := range carriers {
+		list = append(list, models.Carrier{Code: code, Name: c.name})
+	}
+
+	slices.SortFunc(list, func(a, b models.Carrier) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list
+}
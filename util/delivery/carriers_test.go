@@ -0,0 +1,69 @@
+package delivery
+
+import "testing"
+
+func TestValidateTrackingNumber(t *testing.T) {
+	tests := []struct {
+		carrier, trackingNumber string
+		want                    error
+	}{
+		{"royal-mail", "AB123456789GB", nil},
+		{"royal-mail", "AB123456789US", ErrInvalidTrackingNumber},
+		{"royal-mail", "0123456789ABCDEF", nil},
+		{"parcelforce", "AB1234567", nil},
+		{"parcelforce", "AB123456", ErrInvalidTrackingNumber},
+		{"dpd", "12345678901234", nil},
+		{"dpd", "1234567890123", ErrInvalidTrackingNumber},
+		{"evri", "H01ABC0123456789", nil},
+		{"evri", "H01ABC012345678", ErrInvalidTrackingNumber},
+		{"dhl", "1234567890", nil},
+		{"dhl", "12345678901", ErrInvalidTrackingNumber},
+		{"ups", "1Z999AA10123456784", nil},
+		{"ups", "2Z999AA10123456784", ErrInvalidTrackingNumber},
+		{"pigeon", "1234567890", ErrUnknownCarrier},
+		// Patterns are anchored, extra characters either side are refused
+		{"dhl", "x1234567890", ErrInvalidTrackingNumber},
+		{"dhl", "1234567890\n", ErrInvalidTrackingNumber},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateTrackingNumber(tt.carrier, tt.trackingNumber); got != tt.want {
+			t.Errorf("ValidateTrackingNumber(%q, %q) = %v, want %v", tt.carrier, tt.trackingNumber, got, tt.want)
+		}
+	}
+}
+
+func TestNormaliseTrackingNumber(t *testing.T) {
+	tests := map[string]string{
+		"ab 1234 5678 9gb": "AB123456789GB",
+		"1z-999-aa1":       "1Z999AA1",
+		"1234567890":       "1234567890",
+	}
+
+	for in, want := range tests {
+		if got := NormaliseTrackingNumber(in); got != want {
+			t.Errorf("NormaliseTrackingNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTrackingURL(t *testing.T) {
+	if got := TrackingURL("dpd", "12345678901234"); got != "https://track.dpd.co.uk/parcels/12345678901234" {
+		t.Errorf("TrackingURL = %q", got)
+	}
+	if got := TrackingURL("pigeon", "1"); got != "" {
+		t.Errorf("TrackingURL for an unknown carrier = %q, want empty", got)
+	}
+}
+
+func TestFetchCarriersSorted(t *testing.T) {
+	list := FetchCarriers()
+	if len(list) != len(carriers) {
+		t.Fatalf("FetchCarriers returned %d carriers, want %d", len(list), len(carriers))
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Name > list[i].Name {
+			t.Errorf("carriers out of order: %q before %q", list[i-1].Name, list[i].Name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+
+	"github.com/lib/pq"
+)
+
+func FetchRegions(ctx context.Context, tx *sql.Tx) ([]models.Region, error) {
+	regions := make([]models.Region, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT r.region_id, r.name, COALESCE(ARRAY_AGG(rc.country ORDER BY rc.country) FILTER (WHERE rc.country IS NOT NULL), '{}')
+			FROM region r
+			LEFT JOIN region_country rc ON rc.region_id = r.region_id
+			GROUP BY r.region_id, r.name
+			ORDER BY r.name
+		`,
+	)
+	if err != nil {
+		return regions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var region models.Region
+		var countries []string
+		err = rows.Scan(&region.ID, &region.Name, pq.Array(&countries))
+		if err != nil {
+			return regions, err
+		}
+		region.Countries = countries
+
+		regions = append(regions, region)
+	}
+
+	return regions, rows.Err()
+}
@@ -0,0 +1,66 @@
+// Package emailqueue hands emails over to the mailer worker through the Redis lists named by
+// config.HighPriorityEmailQueue and config.LowPriorityEmailQueue.
+//
+// Jobs are pushed with LPUSH, so the worker should consume them with BRPOP to process them in order.
+// Each job is a JSON object:
+//
+//	{
+//	  "template":  "staff-password-reset", // which email to send, see the Template constants
+//	  "to":        "jane@example.com",     // recipient address
+//	  "data":      { ... },                // template variables, listed with each Template constant
+//	  "createdAt": 1727000000000           // unix milliseconds
+//	}
+package emailqueue
+
+import (
+	"context"
+	"encoding/json"
+	"server-api-admin/util/redisclient"
+	"time"
+)
+
+const (
+	// TemplateStaffPasswordReset data:
+	//
+	//	"resetURL":  string, link containing the single-use reset token
+	//	"expiresAt": number, unix milliseconds after which the link stops working
+	TemplateStaffPasswordReset = "staff-password-reset"
+
+	// TemplateStaffInvite data:
+	//
+	//	"acceptURL": string, link containing the single-use invite token
+	//	"expiresAt": number, unix milliseconds after which the invite stops working
+	TemplateStaffInvite = "staff-invite"
+
+	// TemplateOrderDispatched data:
+	//
+	//	"orderID":        number, the customer's order number
+	//	"firstName":      string, may be empty
+	//	"carrier":        string, display name of the carrier
+	//	"trackingNumber": string
+	//	"trackingURL":    string, the carrier's tracking page, empty when the carrier has none
+	//	"dispatchedAt":   number, unix milliseconds
+	TemplateOrderDispatched = "order-dispatched"
+)
+
+type Job struct {
+	Template  string                 `json:"template"`
+	To        string                 `json:"to"`
+	Data      map[string]interface{} `json:"data"`
+	CreatedAt int64                  `json:"createdAt"`
+}
+
+// Enqueue pushes an email job onto the given queue
+func Enqueue(ctx context.Context, queue, template, to string, data map[string]interface{}) error {
+	payload, err := json.Marshal(Job{
+		Template:  template,
+		To:        to,
+		Data:      data,
+		CreatedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return redisclient.Client.LPush(ctx, queue, payload).Err()
+}
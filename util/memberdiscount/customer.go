@@ -0,0 +1,81 @@
+package memberdiscount
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+	"server-api-admin/util/orders"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FetchCustomer returns a customer's accumulated spend, the tier it earns under the current tiers,
+// and every member discount coupon they have been issued. Cancelled and refunded orders don't count.
+func FetchCustomer(ctx context.Context, tx *sql.Tx, userID string) (models.CustomerMemberDiscount, error) {
+	c := models.CustomerMemberDiscount{
+		UserID:  userID,
+		Coupons: make([]models.MemberDiscountCoupon, 0),
+	}
+
+	err := tx.QueryRowContext(
+		ctx,
+		`
+			SELECT
+				u.email,
+				COALESCE((
+					SELECT SUM(co.total_amount_ex_delivery)
+					FROM customer_order co
+					WHERE co.user_id = u.user_id AND co.order_status_id <> ALL($2)
+				), 0)
+			FROM "user" u
+			WHERE u.user_id = $1::uuid
+		`,
+		userID,
+		pq.Array([]int{orders.StatusCancelled, orders.StatusRefunded}),
+	).Scan(&c.Email, &c.AccumulatedSpend)
+	if err != nil {
+		return c, err
+	}
+
+	tiers, err := FetchTiers(ctx, tx, time.Now())
+	if err != nil {
+		return c, err
+	}
+	c.Tier, c.Rate, c.NextTierMinSpend = RateFor(tiers, c.AccumulatedSpend)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT mdc.coupon_id, mdc.discount_rate, mdc.created_at, COALESCE(mdc.order_id_claimed_with, 0), co.order_date
+			FROM member_discount_coupon mdc
+			LEFT JOIN customer_order co ON co.order_id = mdc.order_id_claimed_with
+			WHERE mdc.user_id = $1::uuid
+			ORDER BY mdc.created_at DESC
+		`,
+		userID,
+	)
+	if err != nil {
+		return c, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var coupon models.MemberDiscountCoupon
+		var createdAt time.Time
+		var claimedAt sql.NullTime
+		err = rows.Scan(&coupon.CouponID, &coupon.DiscountRate, &createdAt, &coupon.OrderID, &claimedAt)
+		if err != nil {
+			return c, err
+		}
+
+		coupon.CreatedAt = createdAt.UnixMilli()
+		if claimedAt.Valid {
+			coupon.ClaimedAt = claimedAt.Time.UnixMilli()
+		}
+		c.Coupons = append(c.Coupons, coupon)
+	}
+
+	return c, rows.Err()
+}
@@ -0,0 +1,165 @@
+// Package memberdiscount manages the spend thresholds that earn members a discount.
+//
+// Tiers are saved as whole sets with an effective-from date and are never changed afterwards,
+// so the set in force when an order was placed can always be looked up. The rate a coupon was
+// issued at is also kept on member_discount_coupon.
+package memberdiscount
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"time"
+)
+
+var ErrTierSetExists = errors.New("a tier set already takes effect at that time")
+
+// defaultTiers are the config thresholds, used until the first set is saved
+var defaultTiers = []models.MemberDiscountTier{
+	{Tier: 1, MinSpend: config.MemberDiscountTier1Min, Rate: config.MemberDiscountTier1Rate},
+	{Tier: 2, MinSpend: config.MemberDiscountTier2Min, Rate: config.MemberDiscountTier2Rate},
+	{Tier: 3, MinSpend: config.MemberDiscountTier3Min, Rate: config.MemberDiscountTier3Rate},
+	{Tier: 4, MinSpend: config.MemberDiscountTier4Min, Rate: config.MemberDiscountTier4Rate},
+}
+
+// FetchTiers returns the tiers in force at the given time, lowest first
+func FetchTiers(ctx context.Context, tx *sql.Tx, at time.Time) ([]models.MemberDiscountTier, error) {
+	tiers := make([]models.MemberDiscountTier, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT tier, min_spend, rate
+			FROM member_discount_tier
+			WHERE effective_from = (SELECT MAX(effective_from) FROM member_discount_tier WHERE effective_from <= $1)
+			ORDER BY min_spend ASC
+		`,
+		at,
+	)
+	if err != nil {
+		return tiers, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.MemberDiscountTier
+		err = rows.Scan(&t.Tier, &t.MinSpend, &t.Rate)
+		if err != nil {
+			return tiers, err
+		}
+		tiers = append(tiers, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return tiers, err
+	}
+
+	if len(tiers) == 0 {
+		return defaultTiers, nil
+	}
+	return tiers, nil
+}
+
+// FetchTierSets returns every saved set, newest first, including those that take effect in the future
+func FetchTierSets(ctx context.Context, tx *sql.Tx) ([]models.MemberDiscountTierSet, error) {
+	tierSets := make([]models.MemberDiscountTierSet, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT mdt.effective_from, COALESCE(u.email, ''), mdt.tier, mdt.min_spend, mdt.rate
+			FROM member_discount_tier mdt
+			LEFT JOIN "user" u ON u.user_id = mdt.created_by
+			ORDER BY mdt.effective_from DESC, mdt.min_spend ASC
+		`,
+	)
+	if err != nil {
+		return tierSets, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.MemberDiscountTier
+		var effectiveFrom time.Time
+		var createdBy string
+		err = rows.Scan(&effectiveFrom, &createdBy, &t.Tier, &t.MinSpend, &t.Rate)
+		if err != nil {
+			return tierSets, err
+		}
+
+		if len(tierSets) == 0 || tierSets[len(tierSets)-1].EffectiveFrom != effectiveFrom.UnixMilli() {
+			tierSets = append(tierSets, models.MemberDiscountTierSet{
+				EffectiveFrom: effectiveFrom.UnixMilli(),
+				CreatedBy:     createdBy,
+				Tiers:         make([]models.MemberDiscountTier, 0),
+			})
+		}
+		last := &tierSets[len(tierSets)-1]
+		last.Tiers = append(last.Tiers, t)
+	}
+
+	return tierSets, rows.Err()
+}
+
+// CreateTierSet saves a new set of tiers taking effect at effectiveFrom. Tiers are numbered from 1 in order of spend.
+func CreateTierSet(ctx context.Context, tx *sql.Tx, effectiveFrom time.Time, tiers []models.MemberDiscountTier, userID string) error {
+	// Sets are told apart by effective_from, so two saved for the same time would merge into one.
+	// The table is locked against other writers so concurrent saves can't both pass the check.
+	_, err := tx.ExecContext(ctx, "LOCK TABLE member_discount_tier IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM member_discount_tier WHERE effective_from = $1)",
+		effectiveFrom,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrTierSetExists
+	}
+
+	for i, t := range tiers {
+		_, err := tx.ExecContext(
+			ctx,
+			`
+				INSERT INTO member_discount_tier (effective_from, tier, min_spend, rate, created_at, created_by)
+				VALUES ($1, $2, $3, $4, NOW(), $5::uuid)
+			`,
+			effectiveFrom,
+			i+1,
+			t.MinSpend,
+			t.Rate,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RateFor returns the tier and rate earned by the accumulated spend, and the spend needed for the next tier.
+// The tier is 0 below the first threshold, and the next threshold is 0 once the top tier is reached.
+func RateFor(tiers []models.MemberDiscountTier, accumulatedSpend int) (int, float64, int) {
+	var tier, next int
+	var rate float64
+	for _, t := range tiers {
+		if accumulatedSpend >= t.MinSpend {
+			tier = t.Tier
+			rate = t.Rate
+		} else {
+			next = t.MinSpend
+			break
+		}
+	}
+	return tier, rate, next
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Reason codes sent with 401 and 403 responses, so the frontend can tell why a request was refused
+const (
+	ReasonInvalidSession         = "invalid_session"
+	ReasonInvalidToken           = "invalid_token"
+	ReasonInvalidCSRFToken       = "invalid_csrf_token"
+	ReasonInvalidSignature       = "invalid_signature"
+	ReasonNotSignedIn            = "not_signed_in"
+	ReasonSessionAbsoluteTimeout = "session_absolute_timeout"
+	ReasonInsufficientPermission = "insufficient_permission"
+	ReasonReauthenticationNeeded = "reauthentication_required"
+)
+
+func writeAuthError(w http.ResponseWriter, statusCode int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":  message,
+		"reason": reason,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "sessionID",
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
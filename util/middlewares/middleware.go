@@ -3,24 +3,30 @@ package middlewares
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
 	"net/url"
 	"server-api-admin/config"
-	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/apitokens"
+	"server-api-admin/util/csrf"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/redisclient"
+	"server-api-admin/util/s2s"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-// CustomResponseWriter is a custom implementation of http.ResponseWriter that captures the response body and headers
+// CustomResponseWriter is a custom implementation of http.ResponseWriter that captures the response body and headers.
+// The status code is only recorded here, the middleware writes it once the session cookie has been set.
 type CustomResponseWriter struct {
 	http.ResponseWriter
 	body           bytes.Buffer
 	statusCode     int
 	headersWritten bool
+	sessionEnded   bool
 }
 
 func (w *CustomResponseWriter) Write(b []byte) (int, error) {
@@ -33,90 +39,176 @@ func (w *CustomResponseWriter) Write(b []byte) (int, error) {
 func (w *CustomResponseWriter) WriteHeader(statusCode int) {
 	if !w.headersWritten {
 		w.statusCode = statusCode
-		w.ResponseWriter.WriteHeader(statusCode)
 		w.headersWritten = true
 	}
 }
 
-// Middleware function to handle incoming requests
-func Middleware(next httprouter.Handle) httprouter.Handle {
+// EndSession tells the middleware that the handler has removed the current session,
+// so the session cookie is cleared instead of renewed
+func EndSession(w http.ResponseWriter) {
+	if cw, ok := w.(*CustomResponseWriter); ok {
+		cw.sessionEnded = true
+	}
+}
+
+// Middleware function to handle incoming requests.
+// When any required permissions are given, the request must come from a signed in
+// staff member holding every one of those bits. Pass permissions.SignedIn to only
+// require a session.
+func Middleware(next httprouter.Handle, required ...permissions.Permission) httprouter.Handle {
+	requireSignIn := len(required) > 0
+	requiredPermission := permissions.Combine(required...)
+
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		ctx := r.Context()
 		var sessionID, encryptedSessionID, userID string
-		var expiresAt time.Time
-		var staffPermission int
-		var signedIn bool
+		var expiresAt, reauthenticatedAt time.Time
+		var staffPermission permissions.Permission
+		var signedIn, staleSessionKey, sessionTimedOut, usingAPIToken bool
+
+		// Only our SSR server, signing its requests, may pass the session ID in a header and get it back in the body
+		trustedServer, err := s2s.Authenticate(r, redisclient.Client)
+		if err == s2s.ErrBodyTooLarge {
+			http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		} else if err == s2s.ErrInvalidSignature {
+			writeAuthError(w, http.StatusUnauthorized, ReasonInvalidSignature, "Invalid request signature")
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to check request signature", http.StatusInternalServerError)
+			return
+		}
+		ssr := trustedServer && !isClientRequest(r)
 
 		// Extract session ID from request
-		sessionID = extractSessionID(r)
+		sessionID = extractSessionID(r, ssr)
 
-		// If session ID is missing, create a new one
-		if sessionID != "" {
-			// Start a transaction
-			tx, err := postgresdb.DB.BeginTx(ctx, nil)
-			if err != nil {
-				http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		if token, ok := bearerToken(r); ok {
+			// API tokens stand in for a session, so there is nothing to renew
+			tokenUserID, tokenPermission, err := apitokens.Authenticate(ctx, token)
+			if err == apitokens.ErrInvalidToken {
+				writeAuthError(w, http.StatusUnauthorized, ReasonInvalidToken, "Invalid API token")
+				return
+			} else if err != nil {
+				http.Error(w, "Failed to check API token", http.StatusInternalServerError)
 				return
 			}
-			defer tx.Rollback()
 
+			signedIn = true
+			usingAPIToken = true
+			userID = tokenUserID
+			staffPermission = tokenPermission
+			sessionID = ""
+		} else if sessionID != "" {
 			// Decrypt session ID
-			decryptedSessionID, err := DecryptSessionID(sessionID)
+			decryptedSessionID, stale, err := DecryptSessionID(sessionID)
 			if err != nil {
-				http.Error(w, "Invalid session ID", http.StatusUnauthorized)
+				writeAuthError(w, http.StatusUnauthorized, ReasonInvalidSession, "Invalid session ID")
 				return
 			}
 			sessionID = decryptedSessionID
+			staleSessionKey = stale
 
-			// Get user ID and cart ID from session in a single query
-			userID, staffPermission, err = getUserIDFromSession(ctx, tx, sessionID)
+			// Get the session from the cache or staff_sessions, with its expiry extended
+			session, status, err := resolveSession(ctx, sessionID)
 			if err != nil {
-				http.Error(w, "Failed to get user ID or cart ID from session", http.StatusInternalServerError)
+				http.Error(w, "Failed to get user ID from session", http.StatusInternalServerError)
 				return
 			}
 
-			signedIn = true
+			switch status {
+			case sessionValid:
+				signedIn = true
+				userID = session.UserID
+				staffPermission = session.StaffPermission
+				expiresAt = session.ExpiresAt
+				reauthenticatedAt = session.ReauthenticatedAt
+
+				encryptedSessionID, err = EncryptSessionID(sessionID)
+				if err != nil {
+					log.Printf("Error encrypting session ID: %v", err)
+					http.Error(w, "Failed to encrypt session ID", http.StatusInternalServerError)
+					return
+				}
+			case sessionAbsoluteTimeout:
+				// The session has been removed, so its cookie is cleared below
+				sessionTimedOut = true
+				sessionID = ""
+			default:
+				// The session has expired or been removed, carry on as signed out
+				sessionID = ""
+			}
+		}
 
-			// Extend session expiry based on sliding_expiration
-			encryptedSessionID, expiresAt, err = extendSessionExpiry(ctx, tx, sessionID)
-			if err != nil {
-				http.Error(w, "Failed to extend session expiry", http.StatusInternalServerError)
+		// State-changing requests need a CSRF token bound to the session. Browsers can't attach an API token
+		// or prove they are our SSR server, so those requests are exempt.
+		if !isSafeMethod(r.Method) && !usingAPIToken && !trustedServer {
+			if !csrf.Valid(r.Header.Get(csrf.HeaderName), csrf.Binding(r, sessionID)) {
+				writeAuthError(w, http.StatusForbidden, ReasonInvalidCSRFToken, "Invalid CSRF token")
 				return
 			}
+		}
 
-			// Commit the transaction
-			err = tx.Commit()
-			if err != nil {
-				http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		// Check the staff permission required by the route
+		if requireSignIn {
+			if sessionTimedOut {
+				if !ssr {
+					clearSessionCookie(w)
+				}
+				writeAuthError(w, http.StatusUnauthorized, ReasonSessionAbsoluteTimeout, "Session has reached its maximum age, please sign in again")
+				return
+			}
+			if !signedIn {
+				writeAuthError(w, http.StatusUnauthorized, ReasonNotSignedIn, "Not signed in")
+				return
+			}
+			if staffPermission <= 0 || !staffPermission.Has(requiredPermission) {
+				writeAuthError(w, http.StatusForbidden, ReasonInsufficientPermission, "Insufficient permission")
 				return
 			}
 		}
-		// Add user ID to the request context
+
+		// Add user ID, staff permission and session ID to the request context
 		ctx = context.WithValue(ctx, config.UserIDKey, userID)
+		ctx = context.WithValue(ctx, config.StaffPermissionKey, staffPermission)
+		ctx = context.WithValue(ctx, config.SessionIDKey, sessionID)
+		ctx = context.WithValue(ctx, config.ReauthenticatedKey, reauthenticatedAt)
 
 		// Create a custom response writer to capture the response
-		cw := &CustomResponseWriter{ResponseWriter: w}
+		cw := &CustomResponseWriter{ResponseWriter: w, sessionEnded: sessionTimedOut}
 
 		// Call the next handler with the updated context and custom response writer
 		next(cw, r.WithContext(ctx), ps)
 
+		statusCode := cw.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+
+		if cw.sessionEnded {
+			signedIn = false
+			staffPermission = 0
+		}
+
 		// Combine response data and session info into a single JSON object
 		var combinedResponse map[string]interface{}
 		if cw.body.Len() > 0 {
 			// Try to unmarshal the existing body into a map
 			if err := json.Unmarshal(cw.body.Bytes(), &combinedResponse); err != nil {
-				http.Error(w, "Failed to unmarshal response", http.StatusInternalServerError)
+				// Not a JSON object (e.g. an error message), pass it through untouched
+				w.WriteHeader(statusCode)
+				w.Write(cw.body.Bytes())
 				return
 			}
 		} else {
 			combinedResponse = make(map[string]interface{})
 		}
 
-		if cw.statusCode == 200 && r.Header.Get("X-GET-STAFF-RIGHTS") != "false" {
+		if statusCode == 200 && r.Header.Get("X-GET-STAFF-RIGHTS") != "false" {
 			// Serialize cart content and product details to JSON
 			response := struct {
-				StaffPermission int  `json:"staffPermission"`
-				SignedIn        bool `json:"signedIn"`
+				StaffPermission permissions.Permission `json:"staffPermission"`
+				SignedIn        bool                   `json:"signedIn"`
 			}{
 				StaffPermission: staffPermission,
 				SignedIn:        signedIn,
@@ -128,8 +220,16 @@ func Middleware(next httprouter.Handle) httprouter.Handle {
 		}
 
 		// Set the session cookie or return the session info in the response body
-		if r.Header.Get("X-Renew-Session") != "false" && sessionID != "" && cw.statusCode == 200 {
-			if isClientRequest(r) {
+		if cw.sessionEnded {
+			if !ssr {
+				clearSessionCookie(w)
+			} else {
+				combinedResponse["sessionID"] = ""
+				combinedResponse["expiresAt"] = 0
+			}
+		} else if (r.Header.Get("X-Renew-Session") != "false" || staleSessionKey) && sessionID != "" && statusCode == 200 {
+			// Cookies under a retired key are always re-issued under the active one
+			if !ssr {
 				http.SetCookie(w, &http.Cookie{
 					Name:     "sessionID",
 					Value:    encryptedSessionID,
@@ -157,18 +257,14 @@ func Middleware(next httprouter.Handle) httprouter.Handle {
 		}
 
 		// Write the final response to the client
-		if !cw.headersWritten {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(cw.statusCode)
-		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
 		w.Write(finalResponseJSON)
-
-		postgresdb.DB.Exec("DELETE FROM staff_sessions WHERE expires_at < now()")
 	}
 }
 
-// Helper function to extract session ID from request
-func extractSessionID(r *http.Request) string {
+// Helper function to extract session ID from request, only looking at the X-Session-ID header for SSR requests
+func extractSessionID(r *http.Request, ssr bool) string {
 	var sessionID string
 
 	// Check for session ID in cookies
@@ -182,53 +278,23 @@ func extractSessionID(r *http.Request) string {
 				return ""
 			}
 		}
-	} else {
+	} else if ssr {
 		// Check in headers for SSR requests
-		sessionID = r.Header.Get("X-Session-ID")
+		sessionID = r.Header.Get(s2s.SessionIDHeader)
 	}
 
 	return sessionID
 }
 
-// Helper function to get user ID and cart ID from session in a single query
-func getUserIDFromSession(ctx context.Context, tx *sql.Tx, sessionID string) (string, int, error) {
-	var userID string
-	var staffPermission int
-	err := tx.QueryRowContext(ctx, `
-		SELECT s.user_id, u.staff_permission
-		FROM staff_sessions s
-		JOIN "user" u ON s.user_id = u.user_id
-		WHERE s.session_id = $1
-	`, sessionID).Scan(&userID, &staffPermission)
-	if err != nil {
-		log.Printf("Error getting user ID and staff permission from session: %v", err)
-		return "", 0, err
-	}
-	return userID, staffPermission, nil
+// Helper function to extract an API token from the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
 }
 
-// Helper function to extend session expiry and return the encrypted session ID and expiry datetime
-func extendSessionExpiry(ctx context.Context, tx *sql.Tx, sessionID string) (string, time.Time, error) {
-	var expiresAt time.Time
-
-	err := tx.QueryRowContext(
-		ctx,
-		"UPDATE staff_sessions SET expires_at = NOW() + INTERVAL '15 minutes' WHERE session_id = $1 RETURNING expires_at",
-		sessionID,
-	).Scan(&expiresAt)
-	if err != nil {
-		log.Printf("Error updating session expiry: %v", err)
-		return "", time.Time{}, err
-	}
-
-	// Encrypt the session ID
-	encryptedSessionID, err := EncryptSessionID(sessionID)
-	if err != nil {
-		log.Printf("Error encrypting session ID: %v", err)
-		return "", time.Time{}, err
-	}
-
-	return encryptedSessionID, expiresAt, nil
+// Helper function to check if the request method can't change state
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
 }
 
 // Helper function to check if the request is from a client
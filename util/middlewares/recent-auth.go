@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"server-api-admin/config"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// RequireRecentAuth wraps a handler behind Middleware so that it only runs when the password or a TOTP code
+// was entered for the session within config.ReauthenticationWindow
+func RequireRecentAuth(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !CheckRecentAuth(w, r) {
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+// CheckRecentAuth is the check behind RequireRecentAuth, for handlers where only some requests are sensitive.
+// When it returns false the error response has already been written.
+func CheckRecentAuth(w http.ResponseWriter, r *http.Request) bool {
+	reauthenticatedAt, _ := r.Context().Value(config.ReauthenticatedKey).(time.Time)
+	if reauthenticatedAt.IsZero() || time.Since(reauthenticatedAt) > config.ReauthenticationWindow {
+		writeAuthError(w, http.StatusForbidden, ReasonReauthenticationNeeded, "Please enter your password again to continue")
+		return false
+	}
+	return true
+}
@@ -6,24 +6,21 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"server-api-admin/config"
+	"strings"
+	"time"
 )
 
-var secretKey = []byte(config.SessionIDSecretKey)
+// Bound into every session token as GCM additional data, so it can't be decrypted for any other use
+const sessionIDPurpose = "fm-admin-staff-session"
 
-// EncryptSessionID encrypts the session ID using AES-256 in GCM mode.
+// EncryptSessionID encrypts the session ID using AES-256 in GCM mode with the active key.
+// The result is "<key ID>.<base64 nonce and ciphertext>".
 func EncryptSessionID(sessionID string) (string, error) {
-	if len(secretKey) != 16 && len(secretKey) != 24 && len(secretKey) != 32 {
-		return "", errors.New("invalid key size for AES encryption")
-	}
-
-	block, err := aes.NewCipher(secretKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
+	keyID := config.SessionIDActiveKeyID
+	gcm, err := newGCM(keyID)
 	if err != nil {
 		return "", err
 	}
@@ -33,41 +30,68 @@ func EncryptSessionID(sessionID string) (string, error) {
 		return "", err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(sessionID), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	ciphertext := gcm.Seal(nonce, nonce, []byte(sessionID), additionalData(keyID))
+	return keyID + "." + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// DecryptSessionID decrypts the session ID using AES-256 in GCM mode.
-func DecryptSessionID(encryptedSessionID string) (string, error) {
-	if len(secretKey) != 16 && len(secretKey) != 24 && len(secretKey) != 32 {
-		return "", errors.New("invalid key size for AES decryption")
-	}
+// DecryptSessionID decrypts the session ID using AES-256 in GCM mode with any key still in the ring.
+// stale is true when the token was not encrypted with the active key and should be re-issued.
+func DecryptSessionID(encryptedSessionID string) (sessionID string, stale bool, err error) {
+	keyID, encoded, found := strings.Cut(encryptedSessionID, ".")
 
-	ciphertext, err := base64.StdEncoding.DecodeString(encryptedSessionID)
-	if err != nil {
-		return "", err
+	// Tokens issued before the key ring have no key ID or additional data, and are only read during the migration window
+	var aad []byte
+	if found {
+		aad = additionalData(keyID)
+	} else if time.Now().Before(config.SessionIDLegacyTokensUntil) {
+		keyID, encoded = "0", encryptedSessionID
+	} else {
+		return "", false, errors.New("session ID has no key ID")
 	}
 
-	block, err := aes.NewCipher(secretKey)
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(keyID)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(ciphertext) < nonceSize {
-		return "", errors.New("ciphertext too short")
+		return "", false, errors.New("ciphertext too short")
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	return string(plaintext), nil
+	stale = !found || keyID != config.SessionIDActiveKeyID
+	return string(plaintext), stale, nil
+}
+
+func newGCM(keyID string) (cipher.AEAD, error) {
+	secretKey, ok := config.SessionIDSecretKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown session ID key %q", keyID)
+	}
+
+	if len(secretKey) != 16 && len(secretKey) != 24 && len(secretKey) != 32 {
+		return nil, errors.New("invalid key size for AES encryption")
+	}
+
+	block, err := aes.NewCipher([]byte(secretKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func additionalData(keyID string) []byte {
+	return []byte(sessionIDPurpose + "|" + keyID)
 }
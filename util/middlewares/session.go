@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/sessioncache"
+	"server-api-admin/util/sessionpolicy"
+	"time"
+)
+
+type sessionStatus int
+
+const (
+	sessionValid sessionStatus = iota
+	// Expired through inactivity, or removed from staff_sessions
+	sessionNotFound
+	// Older than the absolute limit of its policy, however active it has been
+	sessionAbsoluteTimeout
+)
+
+// resolveSession returns the session from the cache, or from staff_sessions on a miss, with its expiry slid forward.
+// staff_sessions is only written when its expires_at would fall behind the sliding expiry, and is then pushed a
+// whole idle timeout ahead, so it is written at most once per idle timeout and the reaper never removes a live session.
+// That write also reads back the staff permission, so a demotion reaches cached sessions within one idle timeout
+// even if the cache was never invalidated.
+func resolveSession(ctx context.Context, sessionID string) (sessioncache.Entry, sessionStatus, error) {
+	entry, found, err := sessioncache.Get(ctx, sessionID)
+	if err != nil {
+		log.Printf("Error reading session cache: %v", err)
+	}
+
+	var generation string
+	if !found {
+		entry, generation, err = fetchSessionForCache(ctx, sessionID)
+		if err == sql.ErrNoRows {
+			return entry, sessionNotFound, nil
+		} else if err != nil {
+			log.Printf("Error getting user ID and staff permission from session: %v", err)
+			return entry, sessionNotFound, err
+		}
+	}
+
+	now := time.Now()
+	if !entry.ExpiresAt.After(now) {
+		sessioncache.Invalidate(ctx, entry.UserID, sessionID)
+		return entry, sessionNotFound, nil
+	}
+
+	policy := sessionpolicy.For(entry.StaffPermission)
+	idleTimeout := policy.Idle(entry.RememberDevice)
+	deadline := entry.CreatedAt.Add(policy.Absolute(entry.RememberDevice))
+
+	if !deadline.After(now) {
+		if err := removeSession(ctx, sessionID); err != nil {
+			return entry, sessionNotFound, err
+		}
+		sessioncache.Invalidate(ctx, entry.UserID, sessionID)
+		return entry, sessionAbsoluteTimeout, nil
+	}
+
+	// Never slide past the absolute limit
+	entry.ExpiresAt = now.Add(idleTimeout)
+	if entry.ExpiresAt.After(deadline) {
+		entry.ExpiresAt = deadline
+	}
+
+	if entry.StoredExpiresAt.Before(entry.ExpiresAt) {
+		storedExpiresAt, staffPermission, err := storeSessionExpiry(ctx, sessionID, now.Add(2*idleTimeout))
+		if err == sql.ErrNoRows {
+			// Removed from staff_sessions since it was cached, or expired there. An expired row is never
+			// brought back, so sessions end on time however long the reaper takes.
+			sessioncache.Invalidate(ctx, entry.UserID, sessionID)
+			return entry, sessionNotFound, nil
+		} else if err != nil {
+			log.Printf("Error updating session expiry: %v", err)
+			return entry, sessionNotFound, err
+		}
+		entry.StoredExpiresAt = storedExpiresAt
+		entry.StaffPermission = staffPermission
+	}
+
+	// A hit only touches the entry still in the cache, a miss is cached unless the user's sessions were
+	// invalidated while staff_sessions was being read
+	if found {
+		err = sessioncache.Touch(ctx, sessionID, entry)
+	} else {
+		err = sessioncache.Set(ctx, sessionID, generation, entry)
+	}
+	if err != nil {
+		log.Printf("Error writing session cache: %v", err)
+	}
+
+	return entry, sessionValid, nil
+}
+
+// fetchSessionForCache reads the session along with the cache generation of its user. The generation is read
+// before the full row, so an invalidation committed after that read is always caught by sessioncache.Set.
+func fetchSessionForCache(ctx context.Context, sessionID string) (sessioncache.Entry, string, error) {
+	var userID string
+	err := postgresdb.DB.QueryRowContext(ctx, "SELECT user_id FROM staff_sessions WHERE session_id = $1 AND expires_at > NOW()", sessionID).Scan(&userID)
+	if err != nil {
+		return sessioncache.Entry{}, "", err
+	}
+
+	generation, err := sessioncache.Generation(ctx, userID)
+	if err != nil {
+		return sessioncache.Entry{}, "", err
+	}
+
+	entry, err := fetchSession(ctx, sessionID)
+	return entry, generation, err
+}
+
+func fetchSession(ctx context.Context, sessionID string) (sessioncache.Entry, error) {
+	var entry sessioncache.Entry
+	var reauthenticatedAt sql.NullTime
+	err := postgresdb.DB.QueryRowContext(
+		ctx,
+		`
+			SELECT s.user_id, u.staff_permission, s.expires_at, s.created_at, s.remember_device, s.reauthenticated_at
+			FROM staff_sessions s
+			JOIN "user" u ON s.user_id = u.user_id
+			WHERE s.session_id = $1 AND s.expires_at > NOW()
+		`,
+		sessionID,
+	).Scan(&entry.UserID, &entry.StaffPermission, &entry.ExpiresAt, &entry.CreatedAt, &entry.RememberDevice, &reauthenticatedAt)
+
+	entry.StoredExpiresAt = entry.ExpiresAt
+	if reauthenticatedAt.Valid {
+		entry.ReauthenticatedAt = reauthenticatedAt.Time
+	}
+	return entry, err
+}
+
+func storeSessionExpiry(ctx context.Context, sessionID string, expiresAt time.Time) (time.Time, permissions.Permission, error) {
+	var storedExpiresAt time.Time
+	var staffPermission permissions.Permission
+	err := postgresdb.DB.QueryRowContext(
+		ctx,
+		`
+			UPDATE staff_sessions s
+			SET expires_at = $1
+			FROM "user" u
+			WHERE s.session_id = $2 AND u.user_id = s.user_id AND s.expires_at > NOW()
+			RETURNING s.expires_at, u.staff_permission
+		`,
+		expiresAt,
+		sessionID,
+	).Scan(&storedExpiresAt, &staffPermission)
+	return storedExpiresAt, staffPermission, err
+}
+
+func removeSession(ctx context.Context, sessionID string) error {
+	_, err := postgresdb.DB.ExecContext(ctx, "DELETE FROM staff_sessions WHERE session_id = $1", sessionID)
+	return err
+}
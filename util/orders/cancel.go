@@ -0,0 +1,255 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"server-api-admin/models"
+	"slices"
+)
+
+var (
+	ErrNotCancellable      = errors.New("order can only be cancelled before it is dispatched")
+	ErrUnknownOrderItem    = errors.New("item isn't part of this order")
+	ErrCancelQuantityRange = errors.New("can't cancel more items than are left on the line")
+)
+
+// CancelItem is one line of a partial cancellation
+type CancelItem struct {
+	OrderItemID int `json:"orderItemID"`
+	Quantity    int `json:"quantity"`
+}
+
+// Cancel cancels the given items of an order, or every remaining item when items is empty, and puts them back
+// into inventory_stock at the location they were picked from. Once nothing is left on the order it moves to
+// StatusCancelled, its member discount coupon is released and its voucher stops counting as used.
+// Until then, the value of the cancelled items is taken off the order total.
+//
+// Everything happens in tx, so a dry run is Cancel followed by a rollback.
+// sql.ErrNoRows is returned when the order doesn't exist.
+func Cancel(ctx context.Context, tx *sql.Tx, orderID int, items []CancelItem, actorID, note string) (models.OrderCancellation, error) {
+	c := models.OrderCancellation{
+		OrderID:                 orderID,
+		Restocked:               make([]models.RestockedItem, 0),
+		NotRestocked:            make([]models.RestockedItem, 0),
+		ReleasedMemberCouponIDs: make([]int, 0),
+	}
+
+	var voucherCode sql.NullString
+	err := tx.QueryRowContext(
+		ctx,
+		`
+			SELECT co.order_status_id, vspd.coupon_code
+			FROM customer_order co
+			LEFT JOIN vouchers_storewide_percentage_discount vspd ON vspd.id = co.storewide_pct_coupon_id
+			WHERE co.order_id = $1
+			FOR UPDATE OF co
+		`,
+		orderID,
+	).Scan(&c.FromStatusID, &voucherCode)
+	if err != nil {
+		return c, err
+	}
+
+	if !CanTransition(c.FromStatusID, StatusCancelled) {
+		return c, ErrNotCancellable
+	}
+
+	lines, err := fetchRemainingQuantities(ctx, tx, orderID)
+	if err != nil {
+		return c, err
+	}
+
+	toCancel := make(map[int]int, len(lines))
+	if len(items) == 0 {
+		for id, line := range lines {
+			toCancel[id] = line.remaining
+		}
+	} else {
+		for _, item := range items {
+			line, ok := lines[item.OrderItemID]
+			if !ok {
+				return c, ErrUnknownOrderItem
+			}
+			toCancel[item.OrderItemID] += item.Quantity
+			if item.Quantity <= 0 || toCancel[item.OrderItemID] > line.remaining {
+				return c, ErrCancelQuantityRange
+			}
+		}
+	}
+
+	// Stock rows are always locked in the same order so concurrent cancellations can't deadlock
+	for _, id := range slices.Sorted(maps.Keys(toCancel)) {
+		quantity := toCancel[id]
+		if quantity == 0 {
+			continue
+		}
+		line := lines[id]
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE order_item SET cancelled_quantity = COALESCE(cancelled_quantity, 0) + $1 WHERE order_item_id = $2",
+			quantity,
+			id,
+		)
+		if err != nil {
+			return c, err
+		}
+
+		c.CancelledAmount += line.value(quantity)
+
+		restocked := models.RestockedItem{
+			OrderItemID: id,
+			ProductID:   line.productID,
+			LocationID:  int(line.locationID.Int64),
+			Quantity:    quantity,
+		}
+
+		// Items without a recorded location have to be put away by hand
+		if !line.locationID.Valid {
+			c.NotRestocked = append(c.NotRestocked, restocked)
+			continue
+		}
+
+		err = restock(ctx, tx, line.productID, restocked.LocationID, quantity)
+		if err != nil {
+			return c, err
+		}
+		c.Restocked = append(c.Restocked, restocked)
+	}
+
+	c.FullCancellation = true
+	for id, line := range lines {
+		if line.remaining > toCancel[id] {
+			c.FullCancellation = false
+			break
+		}
+	}
+
+	// What the customer keeps of a partly cancelled order is what counts towards their spend and the vouchers'
+	// minimum spend. A fully cancelled order keeps its totals as charged, its status already leaves it out.
+	c.ToStatusID = c.FromStatusID
+	if !c.FullCancellation {
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE customer_order SET total_amount_ex_delivery = total_amount_ex_delivery - $1 WHERE order_id = $2",
+			c.CancelledAmount,
+			orderID,
+		)
+		return c, err
+	}
+
+	_, err = Transition(ctx, tx, orderID, StatusCancelled, actorID, note)
+	if err != nil {
+		return c, err
+	}
+	c.ToStatusID = StatusCancelled
+
+	// Cancelled orders are left out of voucher usage, so the voucher is free again without touching the order
+	c.ReleasedVoucherCode = voucherCode.String
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			UPDATE member_discount_coupon
+			SET order_id_claimed_with = NULL
+			WHERE order_id_claimed_with = $1
+			RETURNING coupon_id
+		`,
+		orderID,
+	)
+	if err != nil {
+		return c, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var couponID int
+		if err = rows.Scan(&couponID); err != nil {
+			return c, err
+		}
+		c.ReleasedMemberCouponIDs = append(c.ReleasedMemberCouponIDs, couponID)
+	}
+
+	return c, rows.Err()
+}
+
+type orderLine struct {
+	productID  string
+	locationID sql.NullInt64
+	quantity   int
+	remaining  int
+	unitPrice  int
+	discount   int
+}
+
+// value is what the customer paid for the next quantity items cancelled from the line.
+// The discount handed back is worked out from everything cancelled so far, so cancelling in several
+// steps gives back the same discount as cancelling the same items at once.
+func (l orderLine) value(quantity int) int {
+	if l.quantity == 0 {
+		return 0
+	}
+	cancelled := l.quantity - l.remaining
+	discountReturned := l.discount*(cancelled+quantity)/l.quantity - l.discount*cancelled/l.quantity
+	return quantity*l.unitPrice - discountReturned
+}
+
+// fetchRemainingQuantities locks the lines of an order and returns how many items on each are not yet cancelled
+func fetchRemainingQuantities(ctx context.Context, tx *sql.Tx, orderID int) (map[int]orderLine, error) {
+	lines := make(map[int]orderLine)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				order_item_id,
+				product_id,
+				location_id,
+				quantity,
+				quantity - COALESCE(cancelled_quantity, 0),
+				unit_price,
+				COALESCE(discount_amount, 0)
+			FROM order_item
+			WHERE order_id = $1
+			FOR UPDATE
+		`,
+		orderID,
+	)
+	if err != nil {
+		return lines, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var line orderLine
+		err = rows.Scan(&id, &line.productID, &line.locationID, &line.quantity, &line.remaining, &line.unitPrice, &line.discount)
+		if err != nil {
+			return lines, err
+		}
+
+		lines[id] = line
+	}
+
+	return lines, rows.Err()
+}
+
+// restock adds items back to a location, creating the stock row when the location has never held the product
+func restock(ctx context.Context, tx *sql.Tx, productID string, locationID, quantity int) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO inventory_stock (item_id, location_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (item_id, location_id) DO UPDATE SET quantity = inventory_stock.quantity + EXCLUDED.quantity
+		`,
+		productID,
+		locationID,
+		quantity,
+	)
+	return err
+}
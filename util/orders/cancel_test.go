@@ -0,0 +1,26 @@
+package orders
+
+import "testing"
+
+func TestOrderLineValueAddsUp(t *testing.T) {
+	const quantity, unitPrice, discount = 3, 1000, 100
+
+	// Every way of cancelling the whole line in steps must give back exactly the line total
+	for _, steps := range [][]int{{3}, {1, 1, 1}, {1, 2}, {2, 1}} {
+		line := orderLine{quantity: quantity, remaining: quantity, unitPrice: unitPrice, discount: discount}
+		total := 0
+		for _, q := range steps {
+			total += line.value(q)
+			line.remaining -= q
+		}
+		if want := quantity*unitPrice - discount; total != want {
+			t.Errorf("cancelling in steps %v gave back %d, want %d", steps, total, want)
+		}
+	}
+}
+
+func TestOrderLineValueZeroQuantityLine(t *testing.T) {
+	if got := (orderLine{unitPrice: 1000}).value(1); got != 0 {
+		t.Errorf("value on an empty line = %d, want 0", got)
+	}
+}
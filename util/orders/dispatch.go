@@ -0,0 +1,41 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DispatchedOrder holds what the shipping email needs once an order has been dispatched
+type DispatchedOrder struct {
+	OrderID        int
+	Email          string
+	FirstName      string
+	Carrier        string
+	TrackingNumber string
+}
+
+// Dispatch moves an order to StatusDispatched and stores the carrier and tracking number it was sent with.
+// The carrier and tracking number should already have been validated with the delivery package.
+func Dispatch(ctx context.Context, tx *sql.Tx, orderID int, carrier, trackingNumber, actorID string) (DispatchedOrder, int, error) {
+	d := DispatchedOrder{OrderID: orderID, Carrier: carrier, TrackingNumber: trackingNumber}
+
+	from, err := Transition(ctx, tx, orderID, StatusDispatched, actorID, "")
+	if err != nil {
+		return d, from, err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+			UPDATE customer_order
+			SET carrier = $1, tracking_number = $2
+			WHERE order_id = $3
+			RETURNING email, COALESCE(first_name, '')
+		`,
+		carrier,
+		trackingNumber,
+		orderID,
+	).Scan(&d.Email, &d.FirstName)
+
+	return d, from, err
+}
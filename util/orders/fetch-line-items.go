@@ -0,0 +1,74 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+)
+
+// FetchLineItems returns the products in an order with the price and discount they were sold at
+// and the stock location each was picked from
+func FetchLineItems(ctx context.Context, tx *sql.Tx, orderID int) ([]models.OrderLineItem, error) {
+	lineItems := make([]models.OrderLineItem, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				oi.order_item_id,
+				oi.product_id,
+				p.name,
+				COALESCE(img.file_name || img.ext, ''),
+				oi.quantity,
+				COALESCE(oi.cancelled_quantity, 0),
+				oi.unit_price,
+				COALESCE(oi.discount_amount, 0),
+				oi.location_id,
+				COALESCE(la.name, '')
+			FROM order_item oi
+			JOIN product p ON p.product_id = oi.product_id
+			LEFT JOIN LATERAL (
+				SELECT file_name, ext
+				FROM admin_product_image
+				WHERE product_id = oi.product_id
+				ORDER BY sort_order ASC
+				LIMIT 1
+			) img ON true
+			LEFT JOIN location l ON l.location_id = oi.location_id
+			LEFT JOIN location_address la ON la.address_id = l.address_id
+			WHERE oi.order_id = $1
+			ORDER BY oi.order_item_id ASC
+		`,
+		orderID,
+	)
+	if err != nil {
+		return lineItems, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.OrderLineItem
+		var locationID sql.NullInt64
+		err = rows.Scan(
+			&item.OrderItemID,
+			&item.ProductID,
+			&item.Name,
+			&item.AdminImage,
+			&item.Quantity,
+			&item.CancelledQuantity,
+			&item.UnitPrice,
+			&item.Discount,
+			&locationID,
+			&item.LocationName,
+		)
+		if err != nil {
+			return lineItems, err
+		}
+		item.LocationID = int(locationID.Int64)
+
+		lineItems = append(lineItems, item)
+	}
+
+	return lineItems, rows.Err()
+}
@@ -3,47 +3,261 @@ package orders
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"server-api-admin/models"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func FetchOrderOverview(ctx context.Context, tx *sql.Tx, orderStatus int) ([]models.OrderOverviewItem, error) {
+// Columns the overview can be sorted by. Every sort ends with order_id so the keyset is unique.
+const (
+	SortByStatus    = "status"
+	SortByOrderDate = "orderDate"
+	SortByAmount    = "amount"
+)
+
+var sortColumns = map[string][]string{
+	SortByStatus:    {"co.order_status_id", "co.order_date", "co.order_id"},
+	SortByOrderDate: {"co.order_date", "co.order_id"},
+	SortByAmount:    {"co.total_amount_ex_delivery", "co.order_id"},
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OverviewFilter narrows down FetchOrderOverview. Zero values are ignored.
+type OverviewFilter struct {
+	OrderStatusID    int
+	From             time.Time
+	To               time.Time
+	DeliveryMethodID int
+	PaymentMethodID  int
+	RegionID         int
+	Country          string
+	MinAmount        int
+	MaxAmount        int
+	Email            string
+	// Matches an order ID exactly or part of the customer's name
+	Search string
+}
+
+// OverviewPage picks the slice of results FetchOrderOverview returns
+type OverviewPage struct {
+	SortBy     string
+	Descending bool
+	// Cursor from the previous page, empty for the first page
+	After string
+	Limit int
+}
+
+// overviewCursor is the sort key of the last row on a page, along with the sort it was made for
+type overviewCursor struct {
+	SortBy        string    `json:"by"`
+	Descending    bool      `json:"desc"`
+	OrderStatusID int       `json:"s"`
+	OrderDate     time.Time `json:"d"`
+	Amount        int       `json:"a"`
+	OrderID       int       `json:"o"`
+}
+
+// ValidSortBy reports whether the overview can be sorted by the given key
+func ValidSortBy(sortBy string) bool {
+	_, ok := sortColumns[sortBy]
+	return ok
+}
+
+// FetchOrderOverview returns one page of orders matching the filter, the cursor for the next page
+// (empty on the last page) and how many orders match the filter in total.
+func FetchOrderOverview(ctx context.Context, tx *sql.Tx, f OverviewFilter, p OverviewPage) ([]models.OrderOverviewItem, string, int, error) {
 	overviewItems := make([]models.OrderOverviewItem, 0)
-	subquery := ""
-	vars := make([]interface{}, 0)
-	if orderStatus != 0 {
-		subquery = " AND order_status_id = $1"
-		vars = append(vars, orderStatus)
-	}
-	query := fmt.Sprintf(
-		`
-			SELECT order_id, order_status_id, order_date, total_amount_ex_delivery, delivery_method_id 
-			FROM customer_order
-			WHERE 1=1 %s
-			ORDER BY order_status_id ASC, order_date ASC 
-		`,
-		subquery,
-	)
-
-	rows, err := tx.QueryContext(ctx, query, vars...)
+
+	if !ValidSortBy(p.SortBy) {
+		p.SortBy = SortByStatus
+	}
+	columns := sortColumns[p.SortBy]
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.OrderStatusID != 0 {
+		where("co.order_status_id = $%d", f.OrderStatusID)
+	}
+	if !f.From.IsZero() {
+		where("co.order_date >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		where("co.order_date < $%d", f.To)
+	}
+	if f.DeliveryMethodID != 0 {
+		where("co.delivery_method_id = $%d", f.DeliveryMethodID)
+	}
+	if f.PaymentMethodID != 0 {
+		where("co.payment_method_id = $%d", f.PaymentMethodID)
+	}
+	if f.RegionID != 0 {
+		where("rc.region_id = $%d", f.RegionID)
+	}
+	if f.Country != "" {
+		where("LOWER(rc.country) = LOWER($%d)", f.Country)
+	}
+	if f.MinAmount != 0 {
+		where("co.total_amount_ex_delivery >= $%d", f.MinAmount)
+	}
+	if f.MaxAmount != 0 {
+		where("co.total_amount_ex_delivery <= $%d", f.MaxAmount)
+	}
+	if f.Email != "" {
+		where(`co.email ILIKE $%d ESCAPE '\'`, "%"+escapeLike(f.Email)+"%")
+	}
+	if f.Search != "" {
+		if orderID, err := strconv.Atoi(f.Search); err == nil {
+			where("co.order_id = $%d", orderID)
+		} else {
+			where(`CONCAT_WS(' ', co.first_name, co.last_name) ILIKE $%d ESCAPE '\'`, "%"+escapeLike(f.Search)+"%")
+		}
+	}
+
+	from := `
+		FROM customer_order co
+		LEFT JOIN region_country rc ON rc.region_country_id = co.region_country_id
+	`
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// The total ignores the cursor, so it stays the same on every page
+	var total int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*)"+from+whereClause, args...).Scan(&total)
 	if err != nil {
-		return overviewItems, err
+		return overviewItems, "", 0, err
+	}
+
+	if p.After != "" {
+		c, err := decodeCursor(p.After)
+		if err != nil {
+			return overviewItems, "", 0, err
+		}
+
+		// A cursor only points into the order it was made for
+		if c.SortBy != p.SortBy || c.Descending != p.Descending {
+			return overviewItems, "", 0, ErrInvalidCursor
+		}
+
+		values := c.values(p.SortBy)
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+
+		operator := ">"
+		if p.Descending {
+			operator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", ")))
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	direction := " ASC"
+	if p.Descending {
+		direction = " DESC"
+	}
+	orderBy := make([]string, len(columns))
+	for i, column := range columns {
+		orderBy[i] = column + direction
+	}
+
+	args = append(args, p.Limit)
+	query := `
+		SELECT co.order_id, co.order_status_id, co.order_date, co.total_amount_ex_delivery, co.delivery_method_id
+	` + from + whereClause
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", strings.Join(orderBy, ", "), len(args))
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return overviewItems, "", 0, err
 	}
 
 	defer rows.Close()
 
+	var last overviewCursor
 	for rows.Next() {
 		var s models.OrderOverviewItem
 		var orderDate time.Time
 		err = rows.Scan(&s.OrderID, &s.OrderStatusID, &orderDate, &s.TotalOrderAmountExDelivery, &s.DeliveryMethodID)
 		if err != nil {
-			return overviewItems, err
+			return overviewItems, "", 0, err
 		}
 
+		last = overviewCursor{
+			SortBy:        p.SortBy,
+			Descending:    p.Descending,
+			OrderStatusID: s.OrderStatusID,
+			OrderDate:     orderDate,
+			Amount:        s.TotalOrderAmountExDelivery,
+			OrderID:       s.OrderID,
+		}
 		s.OrderDate = orderDate.UnixMilli()
 		overviewItems = append(overviewItems, s)
 	}
+	if err = rows.Err(); err != nil {
+		return overviewItems, "", 0, err
+	}
+
+	var next string
+	if len(overviewItems) == p.Limit {
+		next, err = encodeCursor(last)
+	}
+
+	return overviewItems, next, total, err
+}
+
+// values returns the cursor fields in the order of the sort columns
+func (c overviewCursor) values(sortBy string) []interface{} {
+	switch sortBy {
+	case SortByOrderDate:
+		return []interface{}{c.OrderDate, c.OrderID}
+	case SortByAmount:
+		return []interface{}{c.Amount, c.OrderID}
+	default:
+		return []interface{}{c.OrderStatusID, c.OrderDate, c.OrderID}
+	}
+}
+
+// The cursor carries the full timestamp rather than unix milliseconds, so no rows are skipped or repeated
+// when order_date has finer precision
+func encodeCursor(c overviewCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (overviewCursor, error) {
+	var c overviewCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil || c.OrderID == 0 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
 
-	return overviewItems, nil
+// escapeLike stops user input from being read as LIKE wildcards
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
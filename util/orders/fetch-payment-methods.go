@@ -0,0 +1,30 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+)
+
+func FetchPaymentMethods(ctx context.Context, tx *sql.Tx) ([]models.PaymentMethod, error) {
+	paymentMethods := make([]models.PaymentMethod, 0)
+
+	rows, err := tx.QueryContext(ctx, "SELECT payment_method_id, name FROM payment_method ORDER BY name")
+	if err != nil {
+		return paymentMethods, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.PaymentMethod
+		err = rows.Scan(&p.ID, &p.Name)
+		if err != nil {
+			return paymentMethods, err
+		}
+
+		paymentMethods = append(paymentMethods, p)
+	}
+
+	return paymentMethods, rows.Err()
+}
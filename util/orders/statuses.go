@@ -0,0 +1,38 @@
+package orders
+
+import "slices"
+
+// IDs of the order_status rows the admin API acts on
+const (
+	StatusPaid       = 1
+	StatusPicking    = 2
+	StatusDispatched = 3
+	StatusDelivered  = 4
+	StatusCancelled  = 5
+	StatusRefunded   = 6
+)
+
+// transitions lists the statuses an order may move to from each status.
+// Orders are cancelled before they leave the warehouse and refunded after.
+var transitions = map[int][]int{
+	StatusPaid:       {StatusPicking, StatusCancelled},
+	StatusPicking:    {StatusPaid, StatusDispatched, StatusCancelled},
+	StatusDispatched: {StatusDelivered, StatusRefunded},
+	StatusDelivered:  {StatusRefunded},
+	StatusCancelled:  {},
+	StatusRefunded:   {},
+}
+
+// AllowedTransitions returns the statuses an order in the given status may move to
+func AllowedTransitions(from int) []int {
+	allowed, ok := transitions[from]
+	if !ok {
+		return []int{}
+	}
+	return allowed
+}
+
+// CanTransition reports whether an order may move between the two statuses
+func CanTransition(from, to int) bool {
+	return slices.Contains(AllowedTransitions(from), to)
+}
@@ -0,0 +1,45 @@
+package orders
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	statuses := []int{StatusPaid, StatusPicking, StatusDispatched, StatusDelivered, StatusCancelled, StatusRefunded}
+
+	allowed := map[[2]int]bool{
+		{StatusPaid, StatusPicking}:         true,
+		{StatusPaid, StatusCancelled}:       true,
+		{StatusPicking, StatusPaid}:         true,
+		{StatusPicking, StatusDispatched}:   true,
+		{StatusPicking, StatusCancelled}:    true,
+		{StatusDispatched, StatusDelivered}: true,
+		{StatusDispatched, StatusRefunded}:  true,
+		{StatusDelivered, StatusRefunded}:   true,
+	}
+
+	// Every pair not listed above, including staying in the same status, must be refused
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]int{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%d, %d) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestTerminalStatuses(t *testing.T) {
+	for _, status := range []int{StatusCancelled, StatusRefunded} {
+		if allowed := AllowedTransitions(status); len(allowed) != 0 {
+			t.Errorf("AllowedTransitions(%d) = %v, want none", status, allowed)
+		}
+	}
+}
+
+func TestUnknownStatus(t *testing.T) {
+	if allowed := AllowedTransitions(99); allowed == nil || len(allowed) != 0 {
+		t.Errorf("AllowedTransitions(99) = %#v, want an empty slice", allowed)
+	}
+	if CanTransition(99, StatusPaid) || CanTransition(StatusPaid, 99) {
+		t.Error("CanTransition accepted an unknown status")
+	}
+}
@@ -0,0 +1,105 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server-api-admin/models"
+	"time"
+)
+
+var ErrIllegalTransition = errors.New("order can't move to that status")
+
+// Transition moves an order to a new status, stamping dispatch_datetime or receipt_datetime when it
+// is dispatched or delivered, and records the change in order_status_history. It returns the previous status.
+// sql.ErrNoRows is returned when the order doesn't exist.
+func Transition(ctx context.Context, tx *sql.Tx, orderID, to int, actorID, note string) (int, error) {
+	var from int
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT order_status_id FROM customer_order WHERE order_id = $1 FOR UPDATE",
+		orderID,
+	).Scan(&from)
+	if err != nil {
+		return 0, err
+	}
+
+	if !CanTransition(from, to) {
+		return from, ErrIllegalTransition
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			UPDATE customer_order
+			SET
+				order_status_id = $1,
+				dispatch_datetime = CASE WHEN $1 = $3 THEN COALESCE(dispatch_datetime, NOW()) ELSE dispatch_datetime END,
+				receipt_datetime = CASE WHEN $1 = $4 THEN COALESCE(receipt_datetime, NOW()) ELSE receipt_datetime END
+			WHERE order_id = $2
+		`,
+		to,
+		orderID,
+		StatusDispatched,
+		StatusDelivered,
+	)
+	if err != nil {
+		return from, err
+	}
+
+	var changedBy sql.NullString
+	if actorID != "" {
+		changedBy.Valid = true
+		changedBy.String = actorID
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO order_status_history (order_id, from_status_id, to_status_id, changed_by, changed_at, note)
+			VALUES ($1, $2, $3, $4::uuid, NOW(), $5)
+		`,
+		orderID,
+		from,
+		to,
+		changedBy,
+		note,
+	)
+	return from, err
+}
+
+// FetchStatusHistory returns the status changes of an order, oldest first
+func FetchStatusHistory(ctx context.Context, tx *sql.Tx, orderID int) ([]models.OrderStatusChange, error) {
+	history := make([]models.OrderStatusChange, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT osh.from_status_id, osh.to_status_id, COALESCE(u.email, ''), osh.changed_at, COALESCE(osh.note, '')
+			FROM order_status_history osh
+			LEFT JOIN "user" u ON u.user_id = osh.changed_by
+			WHERE osh.order_id = $1
+			ORDER BY osh.changed_at ASC
+		`,
+		orderID,
+	)
+	if err != nil {
+		return history, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.OrderStatusChange
+		var changedAt time.Time
+		err = rows.Scan(&c.FromStatusID, &c.ToStatusID, &c.ChangedBy, &changedAt, &c.Note)
+		if err != nil {
+			return history, err
+		}
+
+		c.ChangedAt = changedAt.UnixMilli()
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
@@ -1,26 +1,75 @@
 package password
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"server-api-admin/config"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
 
 const (
-	saltLength   = 16
-	timeParam    = 1
-	memoryParam  = 64 * 1024
-	threadsParam = 4
-	keyLength    = 32
+	saltLength = 16
+	keyLength  = 32
 )
 
+// Current hashing policy, set from config once it has been checked
+var (
+	timeParam    uint32
+	memoryParam  uint32
+	threadsParam uint8
+)
+
+func init() {
+	if err := checkParams(config.Argon2Time, config.Argon2MemoryKiB, config.Argon2Threads); err != nil {
+		log.Fatalf("Invalid Argon2 parameters: %v", err)
+	}
+
+	timeParam = uint32(config.Argon2Time)
+	memoryParam = uint32(config.Argon2MemoryKiB)
+	threadsParam = uint8(config.Argon2Threads)
+
+	comparisonDuration = calibrateComparison()
+}
+
+// checkParams rejects values argon2.IDKey would panic on, or that would wrap around when narrowed
+func checkParams(time, memoryKiB, threads int) error {
+	if time < 1 || int64(time) > math.MaxUint32 {
+		return fmt.Errorf("FM_ARGON2_TIME must be between 1 and %d, got %d", uint32(math.MaxUint32), time)
+	}
+	if threads < 1 || threads > math.MaxUint8 {
+		return fmt.Errorf("FM_ARGON2_THREADS must be between 1 and %d, got %d", math.MaxUint8, threads)
+	}
+	if memoryKiB < 8*threads || int64(memoryKiB) > math.MaxUint32 {
+		return fmt.Errorf("FM_ARGON2_MEMORY_KIB must be between 8 × threads and %d, got %d", uint32(math.MaxUint32), memoryKiB)
+	}
+	return nil
+}
+
+type hashParams struct {
+	memory        uint32
+	time          uint32
+	threads       uint8
+	pepperVersion int
+	salt          []byte
+	hash          []byte
+}
+
 func GeneratePasswordHash(password string) (string, string, error) {
+	pepper, ok := config.Peppers[config.PepperVersion]
+	if !ok {
+		return "", "", fmt.Errorf("no pepper configured for version %d", config.PepperVersion)
+	}
+
 	// Generate a random salt
 	salt := make([]byte, saltLength)
 	if _, err := rand.Read(salt); err != nil {
@@ -28,7 +77,7 @@ func GeneratePasswordHash(password string) (string, string, error) {
 	}
 
 	// Combine password and pepper
-	passwordWithPepper := []byte(password + config.Pepper)
+	passwordWithPepper := []byte(password + pepper)
 
 	// Generate the hash
 	hash := argon2.IDKey(passwordWithPepper, salt, timeParam, memoryParam, threadsParam, keyLength)
@@ -37,43 +86,139 @@ func GeneratePasswordHash(password string) (string, string, error) {
 	encodedSalt := base64.StdEncoding.EncodeToString(salt)
 	encodedHash := base64.StdEncoding.EncodeToString(hash)
 
+	// The pepper version is recorded as the PHC keyid parameter, version 0 predates it and leaves it out
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", memoryParam, timeParam, threadsParam)
+	if config.PepperVersion != 0 {
+		params += fmt.Sprintf(",keyid=%d", config.PepperVersion)
+	}
+
 	// Create the complete hash string
-	completeHash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memoryParam, timeParam, threadsParam, encodedSalt, encodedHash)
+	completeHash := fmt.Sprintf("$argon2id$v=%d$%s$%s$%s", argon2.Version, params, encodedSalt, encodedHash)
 
 	return completeHash, encodedSalt, nil
 }
 
 // ComparePasswordWithHash compares the input password with the stored hash
 func ComparePasswordWithHash(password, storedHash, storedSalt string) (bool, error) {
+	p, err := parseHash(storedHash)
+	if err != nil {
+		return false, err
+	}
+
+	pepper, ok := config.Peppers[p.pepperVersion]
+	if !ok {
+		return false, fmt.Errorf("no pepper configured for version %d", p.pepperVersion)
+	}
+
+	// Combine password and pepper
+	passwordWithPepper := []byte(password + pepper)
+
+	// Compute the hash of the input password
+	computedHash := argon2.IDKey(passwordWithPepper, p.salt, p.time, p.memory, p.threads, uint32(len(p.hash)))
+
+	// Compare the computed hash with the stored hash
+	return subtle.ConstantTimeCompare(p.hash, computedHash) == 1, nil
+}
+
+// dummySalt is only used by SimulateComparison, no stored hash is made with it
+var dummySalt = make([]byte, saltLength)
+
+// SimulateComparison spends as long as ComparePasswordWithHash does on a hash made with the current policy.
+// Use it when there is no stored hash to compare against, so timing doesn't reveal why a sign-in failed.
+func SimulateComparison(password string) {
+	argon2.IDKey([]byte(password+config.Peppers[config.PepperVersion]), dummySalt, timeParam, memoryParam, threadsParam, keyLength)
+}
+
+// comparisonDuration is how long one comparison with the current policy took at startup
+var comparisonDuration time.Duration
+
+// calibrateComparison times a few dummy comparisons and keeps the fastest, which is the least skewed by startup noise
+func calibrateComparison() time.Duration {
+	fastest := time.Duration(math.MaxInt64)
+	for range 3 {
+		start := time.Now()
+		SimulateComparison("")
+		fastest = min(fastest, time.Since(start))
+	}
+	return fastest
+}
+
+// WaitComparison waits as long as a comparison takes without doing any hashing.
+// Use it on refused attempts, where spending argon2 memory would let a locked-out caller exhaust it.
+func WaitComparison(ctx context.Context) {
+	select {
+	case <-time.After(comparisonDuration):
+	case <-ctx.Done():
+	}
+}
+
+// NeedsRehash reports whether a stored hash was made with weaker parameters than the current policy,
+// or with a pepper other than the current one
+func NeedsRehash(storedHash string) bool {
+	p, err := parseHash(storedHash)
+	if err != nil {
+		return false
+	}
+
+	return p.memory < memoryParam ||
+		p.time < timeParam ||
+		p.threads < threadsParam ||
+		len(p.hash) < keyLength ||
+		p.pepperVersion != config.PepperVersion
+}
+
+func parseHash(storedHash string) (hashParams, error) {
+	var p hashParams
+
 	// Decode the stored hash parts
 	parts := strings.Split(storedHash, "$")
 	if len(parts) != 6 {
-		return false, errors.New("invalid hash format")
+		return p, errors.New("invalid hash format")
 	}
 
-	var memory, time uint32
-	var threads uint8
-	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
-	if err != nil {
-		return false, err
+	for _, param := range strings.Split(parts[3], ",") {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			return p, errors.New("invalid hash parameters")
+		}
+
+		// Threads are a single byte, anything wider is rejected rather than truncated
+		bitSize := 32
+		if key == "p" {
+			bitSize = 8
+		}
+
+		n, err := strconv.ParseUint(value, 10, bitSize)
+		if err != nil {
+			return p, err
+		}
+
+		switch key {
+		case "m":
+			p.memory = uint32(n)
+		case "t":
+			p.time = uint32(n)
+		case "p":
+			p.threads = uint8(n)
+		case "keyid":
+			p.pepperVersion = int(n)
+		}
 	}
 
-	salt, err := base64.StdEncoding.DecodeString(parts[4])
-	if err != nil {
-		return false, err
+	if p.memory == 0 || p.time == 0 || p.threads == 0 {
+		return p, errors.New("invalid hash parameters")
 	}
 
-	decodedHash, err := base64.StdEncoding.DecodeString(parts[5])
+	var err error
+	p.salt, err = base64.StdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, err
+		return p, err
 	}
 
-	// Combine password and pepper
-	passwordWithPepper := []byte(password + config.Pepper)
-
-	// Compute the hash of the input password
-	computedHash := argon2.IDKey(passwordWithPepper, salt, time, memory, threads, uint32(len(decodedHash)))
+	p.hash, err = base64.StdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, err
+	}
 
-	// Compare the computed hash with the stored hash
-	return subtle.ConstantTimeCompare(decodedHash, computedHash) == 1, nil
+	return p, nil
 }
@@ -0,0 +1,95 @@
+package password
+
+import "testing"
+
+func TestGenerateAndCompare(t *testing.T) {
+	hash, salt, err := GeneratePasswordHash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("GeneratePasswordHash: %v", err)
+	}
+
+	match, err := ComparePasswordWithHash("correct horse battery staple", hash, salt)
+	if err != nil || !match {
+		t.Errorf("ComparePasswordWithHash with the right password = %v, %v", match, err)
+	}
+
+	match, err = ComparePasswordWithHash("wrong horse battery staple", hash, salt)
+	if err != nil || match {
+		t.Errorf("ComparePasswordWithHash with a wrong password = %v, %v", match, err)
+	}
+
+	if NeedsRehash(hash) {
+		t.Error("a fresh hash should not need rehashing")
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	p, err := parseHash("$argon2id$v=19$m=65536,t=3,p=4,keyid=2$c2FsdHNhbHRzYWx0c2FsdA==$aGFzaA==")
+	if err != nil {
+		t.Fatalf("parseHash: %v", err)
+	}
+	if p.memory != 65536 || p.time != 3 || p.threads != 4 || p.pepperVersion != 2 {
+		t.Errorf("parseHash = %+v", p)
+	}
+	if string(p.salt) != "saltsaltsaltsalt" || string(p.hash) != "hash" {
+		t.Errorf("parseHash salt %q hash %q", p.salt, p.hash)
+	}
+}
+
+func TestParseHashRejects(t *testing.T) {
+	const tail = "$c2FsdHNhbHRzYWx0c2FsdA==$aGFzaA=="
+	tests := map[string]string{
+		"too few parts":    "$argon2id$v=19$m=65536,t=3,p=4",
+		"missing value":    "$argon2id$v=19$m=65536,t,p=4" + tail,
+		"zero time":        "$argon2id$v=19$m=65536,t=0,p=4" + tail,
+		"zero threads":     "$argon2id$v=19$m=65536,t=3,p=0" + tail,
+		"threads overflow": "$argon2id$v=19$m=65536,t=3,p=256" + tail,
+		"memory overflow":  "$argon2id$v=19$m=4294967296,t=3,p=4" + tail,
+		"negative":         "$argon2id$v=19$m=-1,t=3,p=4" + tail,
+		"bad salt":         "$argon2id$v=19$m=65536,t=3,p=4$!!!$aGFzaA==",
+	}
+
+	for name, stored := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseHash(stored); err == nil {
+				t.Errorf("parseHash(%q) succeeded", stored)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA==$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g="
+	if !NeedsRehash(weak) {
+		t.Error("a hash with less memory than the policy should need rehashing")
+	}
+
+	if NeedsRehash("not a hash") {
+		t.Error("an unparseable hash should be left alone")
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	tests := []struct {
+		name                     string
+		time, memoryKiB, threads int
+		valid                    bool
+	}{
+		{"defaults", 1, 64 * 1024, 4, true},
+		{"zero time", 0, 64 * 1024, 4, false},
+		{"negative time", -1, 64 * 1024, 4, false},
+		{"zero threads", 1, 64 * 1024, 0, false},
+		{"too many threads", 1, 64 * 1024, 256, false},
+		{"max threads", 1, 8 * 255, 255, true},
+		{"memory below 8 per thread", 1, 31, 4, false},
+		{"memory too large", 1, 1 << 32, 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkParams(tt.time, tt.memoryKiB, tt.threads); (err == nil) != tt.valid {
+				t.Errorf("checkParams(%d, %d, %d) = %v, want valid %v", tt.time, tt.memoryKiB, tt.threads, err, tt.valid)
+			}
+		})
+	}
+}
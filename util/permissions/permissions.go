@@ -0,0 +1,64 @@
+package permissions
+
+import "math"
+
+// Permission is a bitmask stored in "user".staff_permission
+type Permission int64
+
+// Permission bits. The values are persisted in the database, so new bits must
+// only ever be appended to the end of this list.
+const (
+	ViewOrders Permission = 1 << iota
+	EditOrders
+	ViewProducts
+	EditProducts
+	ManageStaff
+	ViewCustomers
+	ManageDiscounts
+	ManageDelivery
+	ViewAuditLog
+	Require2FA
+	ViewMetrics
+)
+
+const (
+	// SignedIn requires a valid staff session without any specific bits
+	SignedIn Permission = 0
+
+	// All is the permission given to the first employee
+	All Permission = math.MaxInt64
+)
+
+type Definition struct {
+	Bit  Permission `json:"bit"`
+	Name string     `json:"name"`
+}
+
+// Catalogue lists every named permission bit, in bit order
+var Catalogue = []Definition{
+	{ViewOrders, "View orders"},
+	{EditOrders, "Edit orders"},
+	{ViewProducts, "View products"},
+	{EditProducts, "Edit products"},
+	{ManageStaff, "Manage staff"},
+	{ViewCustomers, "View customers"},
+	{ManageDiscounts, "Manage discounts"},
+	{ManageDelivery, "Manage delivery"},
+	{ViewAuditLog, "View audit log"},
+	{Require2FA, "Require two-factor authentication"},
+	{ViewMetrics, "View service metrics"},
+}
+
+// Has reports whether p contains every bit in required
+func (p Permission) Has(required Permission) bool {
+	return p&required == required
+}
+
+// Combine ORs the given permissions into a single bitmask
+func Combine(perms ...Permission) Permission {
+	var combined Permission
+	for _, p := range perms {
+		combined |= p
+	}
+	return combined
+}
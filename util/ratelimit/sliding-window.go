@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"server-api-admin/util/redisclient"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var counter atomic.Uint64
+
+// Count returns the number of events recorded under key within the window
+func Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	pipe := redisclient.Client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMicro(), 10))
+	count := pipe.ZCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Add records an event under key and returns the number of events within the window, including this one
+func Add(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	pipe := redisclient.Client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMicro(), 10))
+	pipe.ZAdd(ctx, key, redisZ(now))
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Reset forgets every event recorded under key
+func Reset(ctx context.Context, key string) error {
+	return redisclient.Client.Del(ctx, key).Err()
+}
+
+func redisZ(t time.Time) redis.Z {
+	// The member only needs to be unique, the score is what the window is measured on
+	return redis.Z{
+		Score:  float64(t.UnixMicro()),
+		Member: fmt.Sprintf("%d-%d", t.UnixNano(), counter.Add(1)),
+	}
+}
@@ -0,0 +1,65 @@
+package redislock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"server-api-admin/util/redisclient"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Takes the lock when it is free, or extends it when the caller already holds it
+var acquireScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		return 1
+	end
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return 1
+	end
+	return 0
+`)
+
+// Only removes the lock when the caller still holds it
+var releaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+// Lock is a lease held by a single replica at a time, used for leader election
+type Lock struct {
+	key   string
+	owner string
+	ttl   time.Duration
+}
+
+func New(key string, ttl time.Duration) *Lock {
+	hostname, _ := os.Hostname()
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return &Lock{
+		key:   key,
+		owner: hostname + "-" + hex.EncodeToString(b),
+		ttl:   ttl,
+	}
+}
+
+// TryAcquire takes or renews the lease, and reports whether this replica holds it
+func (l *Lock) TryAcquire(ctx context.Context) (bool, error) {
+	held, err := acquireScript.Run(ctx, redisclient.Client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// Release gives up the lease so that another replica can take over straight away
+func (l *Lock) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, redisclient.Client, []string{l.key}, l.owner).Err()
+}
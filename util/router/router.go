@@ -2,9 +2,15 @@ package router
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"regexp"
 	"server-api-admin/config"
 	"time"
 
@@ -28,20 +34,59 @@ func setSecurityHeaders(next http.Handler) http.Handler {
 		// Set HSTS header (only in production)
 		// w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
 
-		if r.Header.Get("X-Request-Source") != "SSR" {
-			csrfCookie, err := r.Cookie("csrf")
-			if err != nil || csrfCookie.Value != r.Header.Get("X-CSRF-Token") {
-				http.Error(w, "Invalid CSRF Token", http.StatusForbidden)
-				return
-			}
-		}
+		// CSRF tokens are checked in middlewares.Middleware, where the session is known
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// Accepts an ID given by the SSR server or a proxy so that its logs can be matched with ours
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// setRequestID gives every request an ID, available in the request context and echoed in the X-Request-ID header
+func setRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := context.WithValue(r.Context(), config.RequestIDKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loadTLSConfig returns nil when the API is served over plain HTTP. Client certificates are optional,
+// s2s.Authenticate checks who presented one.
+func loadTLSConfig() (*tls.Config, error) {
+	if config.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSClientCAFile != "" {
+		caPEM, err := os.ReadFile(config.TLSClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", config.TLSClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
 func Listen(ctx context.Context) {
-	handler := setSecurityHeaders(Router)
+	handler := setRequestID(setSecurityHeaders(Router))
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%s", config.APIPort),
 		Handler:      handler,
@@ -50,8 +95,20 @@ func Listen(ctx context.Context) {
 		IdleTimeout:  15 * time.Second,
 	}
 
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		log.Fatalf("Could not load TLS configuration: %v\n", err)
+	}
+	server.TLSConfig = tlsConfig
+
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Could not listen on %s: %v\n", config.APIPort, err)
 		}
 	}()
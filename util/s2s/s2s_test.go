@@ -0,0 +1,196 @@
+package s2s
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server-api-admin/config"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryStore stands in for Redis, keys never expire
+type memoryStore struct {
+	seen map[string]bool
+	err  error
+}
+
+func (m *memoryStore) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if m.err != nil {
+		return redis.NewBoolResult(false, m.err)
+	}
+	if m.seen[key] {
+		return redis.NewBoolResult(false, nil)
+	}
+	m.seen[key] = true
+	return redis.NewBoolResult(true, nil)
+}
+
+func newStore() *memoryStore {
+	return &memoryStore{seen: make(map[string]bool)}
+}
+
+func withConfig(t *testing.T) {
+	t.Helper()
+	secret, window := config.S2SSharedSecret, config.S2SSignatureWindow
+	config.S2SSharedSecret = "test-secret"
+	config.S2SSignatureWindow = 30 * time.Second
+	t.Cleanup(func() {
+		config.S2SSharedSecret, config.S2SSignatureWindow = secret, window
+	})
+}
+
+// signedRequest builds a request signed the way the SSR server signs it
+func signedRequest(method, uri, sessionID, body string, at time.Time) *http.Request {
+	r := httptest.NewRequest(method, uri, strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	r.Header.Set(TimestampHeader, timestamp)
+	if sessionID != "" {
+		r.Header.Set(SessionIDHeader, sessionID)
+	}
+	r.Header.Set(SignatureHeader, hex.EncodeToString(sign(method, r.URL.RequestURI(), timestamp, sessionID, []byte(body))))
+	return r
+}
+
+func TestAuthenticateValid(t *testing.T) {
+	withConfig(t)
+
+	r := signedRequest(http.MethodPost, "/admin/order-init?x=1", "session-id", `{"orderID":1}`, time.Now())
+	trusted, err := Authenticate(r, newStore())
+	if err != nil || !trusted {
+		t.Fatalf("Authenticate = %v, %v, want true", trusted, err)
+	}
+
+	// The handler still gets the whole body
+	body, _ := io.ReadAll(r.Body)
+	if string(body) != `{"orderID":1}` {
+		t.Errorf("body after Authenticate = %q", body)
+	}
+}
+
+func TestAuthenticateUnsigned(t *testing.T) {
+	withConfig(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/csrf-token", nil)
+	trusted, err := Authenticate(r, newStore())
+	if err != nil || trusted {
+		t.Errorf("Authenticate = %v, %v, want false without an error", trusted, err)
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	withConfig(t)
+
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+	}{
+		{"stale", func(r *http.Request) {
+			*r = *signedRequest(http.MethodPost, "/admin/x", "", "{}", time.Now().Add(-31*time.Second))
+		}},
+		{"from the future", func(r *http.Request) {
+			*r = *signedRequest(http.MethodPost, "/admin/x", "", "{}", time.Now().Add(31*time.Second))
+		}},
+		{"timestamp changed", func(r *http.Request) {
+			r.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Unix()-1, 10))
+		}},
+		{"timestamp not a number", func(r *http.Request) {
+			r.Header.Set(TimestampHeader, "now")
+		}},
+		{"body changed", func(r *http.Request) {
+			r.Body = io.NopCloser(strings.NewReader(`{"orderID":2}`))
+		}},
+		{"path changed", func(r *http.Request) {
+			r.URL.Path = "/admin/other"
+			r.RequestURI = "/admin/other"
+		}},
+		{"method changed", func(r *http.Request) {
+			r.Method = http.MethodDelete
+		}},
+		{"session ID added", func(r *http.Request) {
+			r.Header.Set(SessionIDHeader, "someone-else")
+		}},
+		{"signature not hex", func(r *http.Request) {
+			r.Header.Set(SignatureHeader, "zz")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signedRequest(http.MethodPost, "/admin/x", "", `{"orderID":1}`, time.Now())
+			tt.modify(r)
+			if _, err := Authenticate(r, newStore()); err != ErrInvalidSignature {
+				t.Errorf("Authenticate = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateSignedSessionID(t *testing.T) {
+	withConfig(t)
+
+	r := signedRequest(http.MethodPost, "/admin/x", "session-a", "{}", time.Now())
+	r.Header.Set(SessionIDHeader, "session-b")
+	if _, err := Authenticate(r, newStore()); err != ErrInvalidSignature {
+		t.Errorf("swapped X-Session-ID: Authenticate = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestAuthenticateWithoutSecret(t *testing.T) {
+	withConfig(t)
+	r := signedRequest(http.MethodPost, "/admin/x", "", "{}", time.Now())
+
+	config.S2SSharedSecret = ""
+	if _, err := Authenticate(r, newStore()); err != ErrInvalidSignature {
+		t.Errorf("Authenticate = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestAuthenticateReplay(t *testing.T) {
+	withConfig(t)
+	store := newStore()
+	at := time.Now()
+
+	if trusted, err := Authenticate(signedRequest(http.MethodPost, "/admin/x", "", "{}", at), store); err != nil || !trusted {
+		t.Fatalf("first use: Authenticate = %v, %v", trusted, err)
+	}
+
+	if _, err := Authenticate(signedRequest(http.MethodPost, "/admin/x", "", "{}", at), store); err != ErrInvalidSignature {
+		t.Errorf("replay: Authenticate = %v, want ErrInvalidSignature", err)
+	}
+
+	// hex.DecodeString accepts either case, the replay check must not
+	r := signedRequest(http.MethodPost, "/admin/x", "", "{}", at)
+	r.Header.Set(SignatureHeader, strings.ToUpper(r.Header.Get(SignatureHeader)))
+	if _, err := Authenticate(r, store); err != ErrInvalidSignature {
+		t.Errorf("replay in upper case: Authenticate = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestAuthenticateStoreError(t *testing.T) {
+	withConfig(t)
+	storeErr := errors.New("redis is down")
+
+	r := signedRequest(http.MethodPost, "/admin/x", "", "{}", time.Now())
+	if _, err := Authenticate(r, &memoryStore{err: storeErr}); err != storeErr {
+		t.Errorf("Authenticate = %v, want the store error", err)
+	}
+}
+
+func TestAuthenticateBodyTooLarge(t *testing.T) {
+	withConfig(t)
+
+	r := signedRequest(http.MethodPost, "/admin/x", "", "", time.Now())
+	r.Body = io.NopCloser(bytes.NewReader(make([]byte, maxBodySize+1)))
+	if _, err := Authenticate(r, newStore()); err != ErrBodyTooLarge {
+		t.Errorf("Authenticate = %v, want ErrBodyTooLarge", err)
+	}
+}
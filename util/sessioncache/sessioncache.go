@@ -0,0 +1,230 @@
+package sessioncache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"server-api-admin/config"
+	"server-api-admin/util/permissions"
+	"server-api-admin/util/redisclient"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// How long the per-user index and generation outlive their last update. Stale members only cost a no-op delete.
+const userIndexTTL = 24 * time.Hour
+
+// Entry is the cached state of a staff session
+type Entry struct {
+	UserID          string
+	StaffPermission permissions.Permission
+	// Sliding expiry as seen by the client
+	ExpiresAt time.Time
+	// expires_at as last written to staff_sessions, never earlier than ExpiresAt
+	StoredExpiresAt time.Time
+	CreatedAt       time.Time
+	RememberDevice  bool
+	// Last time the password or a TOTP code was entered for this session
+	ReauthenticatedAt time.Time
+}
+
+// Only caches the session when the user's generation is still the one read before staff_sessions was queried,
+// so a request racing an invalidation can't put the old row back
+var setScript = redis.NewScript(`
+	local generation = redis.call("GET", KEYS[3]) or "0"
+	if generation ~= ARGV[1] then
+		return 0
+	end
+	redis.call("HSET", KEYS[1],
+		"userID", ARGV[4],
+		"staffPermission", ARGV[5],
+		"expiresAt", ARGV[6],
+		"storedExpiresAt", ARGV[7],
+		"createdAt", ARGV[8],
+		"rememberDevice", ARGV[9],
+		"reauthenticatedAt", ARGV[10])
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	redis.call("SADD", KEYS[2], ARGV[11])
+	redis.call("PEXPIRE", KEYS[2], ARGV[3])
+	return 1
+`)
+
+// Only updates a session that is still cached, an invalidated one is never brought back
+var touchScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	redis.call("HSET", KEYS[1], "expiresAt", ARGV[2], "storedExpiresAt", ARGV[3], "staffPermission", ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	return 1
+`)
+
+// keyID hashes the session ID, so the session IDs themselves are never stored in Redis
+func keyID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionKey(sessionID string) string {
+	return config.RedisKeyPrefixSessionCache + keyID(sessionID)
+}
+
+func userKey(userID string) string {
+	return config.RedisKeyPrefixUserSessions + userID
+}
+
+func generationKey(userID string) string {
+	return config.RedisKeyPrefixSessionGeneration + userID
+}
+
+// Get returns the cached session, found is false on a cache miss
+func Get(ctx context.Context, sessionID string) (entry Entry, found bool, err error) {
+	values, err := redisclient.Client.HGetAll(ctx, sessionKey(sessionID)).Result()
+	if err != nil || len(values) == 0 {
+		return entry, false, err
+	}
+
+	staffPermission, err := strconv.ParseInt(values["staffPermission"], 10, 64)
+	if err != nil {
+		return entry, false, err
+	}
+
+	expiresAt, err := strconv.ParseInt(values["expiresAt"], 10, 64)
+	if err != nil {
+		return entry, false, err
+	}
+
+	storedExpiresAt, err := strconv.ParseInt(values["storedExpiresAt"], 10, 64)
+	if err != nil {
+		return entry, false, err
+	}
+
+	createdAt, err := strconv.ParseInt(values["createdAt"], 10, 64)
+	if err != nil {
+		return entry, false, err
+	}
+
+	// Entries cached before the field existed are read as never reauthenticated
+	var reauthenticatedAt int64
+	if v, ok := values["reauthenticatedAt"]; ok {
+		reauthenticatedAt, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return entry, false, err
+		}
+	}
+
+	entry.UserID = values["userID"]
+	entry.StaffPermission = permissions.Permission(staffPermission)
+	entry.ExpiresAt = time.UnixMilli(expiresAt)
+	entry.StoredExpiresAt = time.UnixMilli(storedExpiresAt)
+	entry.CreatedAt = time.UnixMilli(createdAt)
+	entry.RememberDevice = values["rememberDevice"] == "1"
+	if reauthenticatedAt != 0 {
+		entry.ReauthenticatedAt = time.UnixMilli(reauthenticatedAt)
+	}
+
+	return entry, true, nil
+}
+
+// Generation returns the user's invalidation counter. Read it before querying staff_sessions and pass it to Set.
+func Generation(ctx context.Context, userID string) (string, error) {
+	generation, err := redisclient.Client.Get(ctx, generationKey(userID)).Result()
+	if err == redis.Nil {
+		return "0", nil
+	}
+	return generation, err
+}
+
+// Set caches a session read from staff_sessions until it expires and indexes it under its user for invalidation.
+// Nothing is cached when the user's sessions have been invalidated since generation was read.
+func Set(ctx context.Context, sessionID, generation string, entry Entry) error {
+	ttl := time.Until(entry.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	rememberDevice := "0"
+	if entry.RememberDevice {
+		rememberDevice = "1"
+	}
+
+	return setScript.Run(
+		ctx,
+		redisclient.Client,
+		[]string{sessionKey(sessionID), userKey(entry.UserID), generationKey(entry.UserID)},
+		generation,
+		ttl.Milliseconds(),
+		userIndexTTL.Milliseconds(),
+		entry.UserID,
+		int64(entry.StaffPermission),
+		entry.ExpiresAt.UnixMilli(),
+		entry.StoredExpiresAt.UnixMilli(),
+		entry.CreatedAt.UnixMilli(),
+		rememberDevice,
+		unixMilli(entry.ReauthenticatedAt),
+		keyID(sessionID),
+	).Err()
+}
+
+// Touch slides the expiry of a cached session and refreshes the permission read back from staff_sessions.
+// A session that has been invalidated in the meantime stays uncached.
+func Touch(ctx context.Context, sessionID string, entry Entry) error {
+	ttl := time.Until(entry.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return touchScript.Run(
+		ctx,
+		redisclient.Client,
+		[]string{sessionKey(sessionID)},
+		ttl.Milliseconds(),
+		entry.ExpiresAt.UnixMilli(),
+		entry.StoredExpiresAt.UnixMilli(),
+		int64(entry.StaffPermission),
+	).Err()
+}
+
+// Invalidate drops the given sessions of a user from the cache. Call it after the change to staff_sessions
+// has been committed, the bumped generation stops requests already in flight from caching the old row again.
+func Invalidate(ctx context.Context, userID string, sessionIDs ...string) error {
+	pipe := redisclient.Client.TxPipeline()
+	pipe.Incr(ctx, generationKey(userID))
+	pipe.Expire(ctx, generationKey(userID), userIndexTTL)
+	for _, sessionID := range sessionIDs {
+		pipe.Del(ctx, sessionKey(sessionID))
+		pipe.SRem(ctx, userKey(userID), keyID(sessionID))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateUser drops every cached session of a user, e.g. after their permission changed
+func InvalidateUser(ctx context.Context, userID string) error {
+	if err := redisclient.Client.Incr(ctx, generationKey(userID)).Err(); err != nil {
+		return err
+	}
+	redisclient.Client.Expire(ctx, generationKey(userID), userIndexTTL)
+
+	keyIDs, err := redisclient.Client.SMembers(ctx, userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(keyIDs)+1)
+	for _, id := range keyIDs {
+		keys = append(keys, config.RedisKeyPrefixSessionCache+id)
+	}
+	keys = append(keys, userKey(userID))
+	return redisclient.Client.Del(ctx, keys...).Err()
+}
+
+// unixMilli keeps the zero time as 0 rather than a large negative number
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,64 @@
+package sessionpolicy
+
+import (
+	"server-api-admin/config"
+	"server-api-admin/util/permissions"
+	"time"
+)
+
+// Staff holding any of these bits get the stricter privileged policy
+const PrivilegedPermission = permissions.ManageStaff
+
+type Policy struct {
+	IdleTimeout                   time.Duration
+	AbsoluteTimeout               time.Duration
+	RememberDeviceIdleTimeout     time.Duration
+	RememberDeviceAbsoluteTimeout time.Duration
+}
+
+var (
+	staffPolicy = Policy{
+		IdleTimeout:                   config.SessionIdleTimeout,
+		AbsoluteTimeout:               config.SessionAbsoluteTimeout,
+		RememberDeviceIdleTimeout:     config.SessionRememberDeviceIdleTimeout,
+		RememberDeviceAbsoluteTimeout: config.SessionRememberDeviceAbsoluteTimeout,
+	}
+
+	privilegedPolicy = Policy{
+		IdleTimeout:                   config.PrivilegedSessionIdleTimeout,
+		AbsoluteTimeout:               config.PrivilegedSessionAbsoluteTimeout,
+		RememberDeviceIdleTimeout:     config.PrivilegedSessionIdleTimeout,
+		RememberDeviceAbsoluteTimeout: config.PrivilegedSessionAbsoluteTimeout,
+	}
+)
+
+func init() {
+	if config.PrivilegedSessionRememberDeviceAllowed {
+		privilegedPolicy.RememberDeviceIdleTimeout = config.SessionRememberDeviceIdleTimeout
+		privilegedPolicy.RememberDeviceAbsoluteTimeout = config.SessionRememberDeviceAbsoluteTimeout
+	}
+}
+
+// For returns the session policy that applies to a staff permission
+func For(staffPermission permissions.Permission) Policy {
+	if staffPermission&PrivilegedPermission != 0 {
+		return privilegedPolicy
+	}
+	return staffPolicy
+}
+
+// Idle returns how long a session may go unused
+func (p Policy) Idle(rememberDevice bool) time.Duration {
+	if rememberDevice {
+		return p.RememberDeviceIdleTimeout
+	}
+	return p.IdleTimeout
+}
+
+// Absolute returns the maximum age of a session, however active it is
+func (p Policy) Absolute(rememberDevice bool) time.Duration {
+	if rememberDevice {
+		return p.RememberDeviceAbsoluteTimeout
+	}
+	return p.AbsoluteTimeout
+}
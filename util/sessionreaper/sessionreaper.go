@@ -0,0 +1,72 @@
+package sessionreaper
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"server-api-admin/config"
+	"server-api-admin/util/postgresdb"
+	"server-api-admin/util/redislock"
+	"time"
+)
+
+var (
+	rowsReaped = expvar.NewInt("session_reaper_rows_reaped_total")
+	runs       = expvar.NewInt("session_reaper_runs_total")
+	failures   = expvar.NewInt("session_reaper_failures_total")
+	lastRunAt  = expvar.NewInt("session_reaper_last_run_unix_ms")
+)
+
+// Run deletes expired staff sessions every config.SessionReaperInterval until ctx is cancelled.
+// Only the replica holding the Redis lock does the work.
+func Run(ctx context.Context) {
+	interval := config.SessionReaperInterval
+	// The lease outlives one tick, so the leader keeps it as long as it is running
+	lock := redislock.New(config.RedisKeySessionReaperLock, 2*interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			if err := lock.Release(releaseCtx); err != nil {
+				log.Printf("Error releasing session reaper lock: %v", err)
+			}
+			cancel()
+			log.Println("Session reaper stopped")
+			return
+		case <-ticker.C:
+			leader, err := lock.TryAcquire(ctx)
+			if err != nil {
+				log.Printf("Error acquiring session reaper lock: %v", err)
+				continue
+			}
+			if leader {
+				reap(ctx, interval)
+			}
+		}
+	}
+}
+
+func reap(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	runs.Add(1)
+	lastRunAt.Set(time.Now().UnixMilli())
+
+	result, err := postgresdb.DB.ExecContext(ctx, "DELETE FROM staff_sessions WHERE expires_at < NOW()")
+	if err != nil {
+		failures.Add(1)
+		log.Printf("Error reaping expired staff sessions: %v", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	rowsReaped.Add(n)
+}
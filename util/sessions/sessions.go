@@ -0,0 +1,99 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+	"server-api-admin/util/tokens"
+	"time"
+)
+
+// Handle returns the opaque identifier used to refer to a session outside of its cookie
+func Handle(sessionID string) string {
+	return tokens.Hash(sessionID)[:16]
+}
+
+func FetchUserSessions(ctx context.Context, tx *sql.Tx, userID, currentSessionID string) ([]models.StaffSession, error) {
+	staffSessions := make([]models.StaffSession, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT session_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, expires_at
+			FROM staff_sessions
+			WHERE user_id = $1::uuid AND expires_at > NOW()
+			ORDER BY created_at DESC
+		`,
+		userID,
+	)
+	if err != nil {
+		return staffSessions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.StaffSession
+		var sessionID string
+		var createdAt, expiresAt time.Time
+		err = rows.Scan(&sessionID, &s.UserAgent, &s.IPAddress, &createdAt, &expiresAt)
+		if err != nil {
+			return staffSessions, err
+		}
+
+		s.Handle = Handle(sessionID)
+		s.CreatedAt = createdAt.UnixMilli()
+		s.ExpiresAt = expiresAt.UnixMilli()
+		s.Current = sessionID == currentSessionID
+		staffSessions = append(staffSessions, s)
+	}
+
+	return staffSessions, rows.Err()
+}
+
+// FindSessionByHandle returns the ID of the user's session with the given handle, or an empty string if there is none
+func FindSessionByHandle(ctx context.Context, tx *sql.Tx, userID, handle string) (string, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT session_id FROM staff_sessions WHERE user_id = $1::uuid",
+		userID,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var sessionID string
+		err = rows.Scan(&sessionID)
+		if err != nil {
+			return "", err
+		}
+
+		if Handle(sessionID) == handle {
+			return sessionID, nil
+		}
+	}
+
+	return "", rows.Err()
+}
+
+func RevokeSession(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM staff_sessions WHERE session_id = $1", sessionID)
+	return err
+}
+
+// RevokeUserSessions removes every session of a user, apart from exceptSessionID when it is not empty
+func RevokeUserSessions(ctx context.Context, tx *sql.Tx, userID, exceptSessionID string) (int64, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM staff_sessions WHERE user_id = $1::uuid AND session_id::text <> $2",
+		userID,
+		exceptSessionID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,78 @@
+// Package settings stores business settings that staff can change without a redeploy.
+// Each setting has a default in config that applies until a value is saved.
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const (
+	StaffDiscountRate = "staff_discount_rate"
+	StaffMonthlyQuota = "staff_monthly_quota"
+)
+
+// Fetch returns the stored values of the given keys. Keys without a stored value are left out.
+func Fetch(ctx context.Context, tx *sql.Tx, keys ...string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT setting_key, value FROM admin_setting WHERE setting_key = ANY($1)",
+		pq.Array(keys),
+	)
+	if err != nil {
+		return values, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return values, err
+		}
+		values[key] = value
+	}
+
+	return values, rows.Err()
+}
+
+// Save stores a value, recording who changed it
+func Save(ctx context.Context, tx *sql.Tx, key, value, userID string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO admin_setting (setting_key, value, updated_at, updated_by)
+			VALUES ($1, $2, NOW(), $3::uuid)
+			ON CONFLICT (setting_key) DO UPDATE
+			SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at, updated_by = EXCLUDED.updated_by
+		`,
+		key,
+		value,
+		userID,
+	)
+	return err
+}
+
+// Float parses a stored value, falling back to the default when it is missing or invalid
+func Float(values map[string]string, key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(values[key], 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// Int parses a stored value, falling back to the default when it is missing or invalid
+func Int(values map[string]string, key string, fallback int) int {
+	v, err := strconv.Atoi(values[key])
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+package staff
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+	"server-api-admin/util/permissions"
+	"time"
+)
+
+func FetchStaff(ctx context.Context, tx *sql.Tx) ([]models.StaffMember, error) {
+	staffMembers := make([]models.StaffMember, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT user_id, email, staff_permission
+			FROM "user"
+			WHERE staff_permission > 0
+			ORDER BY email ASC
+		`,
+	)
+	if err != nil {
+		return staffMembers, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.StaffMember
+		err = rows.Scan(&s.UserID, &s.Email, &s.StaffPermission)
+		if err != nil {
+			return staffMembers, err
+		}
+		staffMembers = append(staffMembers, s)
+	}
+
+	return staffMembers, rows.Err()
+}
+
+func FetchPendingInvites(ctx context.Context, tx *sql.Tx) ([]models.StaffInvite, error) {
+	invites := make([]models.StaffInvite, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT si.invite_id, si.email, si.staff_permission, COALESCE(u.email, ''), si.expires_at
+			FROM staff_invite si
+			LEFT JOIN "user" u ON u.user_id = si.invited_by
+			WHERE si.redeemed_at IS NULL AND si.expires_at > NOW()
+			ORDER BY si.expires_at ASC
+		`,
+	)
+	if err != nil {
+		return invites, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.StaffInvite
+		var expiresAt time.Time
+		err = rows.Scan(&i.InviteID, &i.Email, &i.StaffPermission, &i.InvitedBy, &expiresAt)
+		if err != nil {
+			return invites, err
+		}
+		i.ExpiresAt = expiresAt.UnixMilli()
+		invites = append(invites, i)
+	}
+
+	return invites, rows.Err()
+}
+
+// FetchStaffPermission returns the current staff permission of a user
+func FetchStaffPermission(ctx context.Context, tx *sql.Tx, userID string) (permissions.Permission, error) {
+	var staffPermission permissions.Permission
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT staff_permission FROM "user" WHERE user_id = $1::uuid`,
+		userID,
+	).Scan(&staffPermission)
+	return staffPermission, err
+}
+
+// FetchEmail returns the email address a user signs in with
+func FetchEmail(ctx context.Context, tx *sql.Tx, userID string) (string, error) {
+	var email string
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT email FROM "user" WHERE user_id = $1::uuid`,
+		userID,
+	).Scan(&email)
+	return email, err
+}
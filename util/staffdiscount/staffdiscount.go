@@ -0,0 +1,158 @@
+// Package staffdiscount tracks the discounted items staff buy against their monthly quota.
+// Orders count towards the month they were placed in, unless they were cancelled or refunded.
+package staffdiscount
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/config"
+	"server-api-admin/models"
+	"server-api-admin/util/orders"
+	"server-api-admin/util/settings"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Reasons a discount is refused, returned by Validate
+const (
+	ReasonNotStaff      = "not_staff"
+	ReasonQuotaExceeded = "quota_exceeded"
+)
+
+// Orders in these statuses don't use up quota
+var excludedStatuses = pq.Array([]int{orders.StatusCancelled, orders.StatusRefunded})
+
+// FetchSettings returns the current rate and quota, using the config defaults for anything not saved
+func FetchSettings(ctx context.Context, tx *sql.Tx) (models.StaffDiscountSettings, error) {
+	values, err := settings.Fetch(ctx, tx, settings.StaffDiscountRate, settings.StaffMonthlyQuota)
+	if err != nil {
+		return models.StaffDiscountSettings{}, err
+	}
+
+	return models.StaffDiscountSettings{
+		Rate:         settings.Float(values, settings.StaffDiscountRate, config.StaffDiscountRate),
+		MonthlyQuota: settings.Int(values, settings.StaffMonthlyQuota, config.StaffMonthlyQuota),
+	}, nil
+}
+
+func SaveSettings(ctx context.Context, tx *sql.Tx, s models.StaffDiscountSettings, userID string) error {
+	err := settings.Save(ctx, tx, settings.StaffDiscountRate, strconv.FormatFloat(s.Rate, 'f', -1, 64), userID)
+	if err != nil {
+		return err
+	}
+	return settings.Save(ctx, tx, settings.StaffMonthlyQuota, strconv.Itoa(s.MonthlyQuota), userID)
+}
+
+// MonthBounds returns the start of the month t falls in and the start of the next one, in UTC
+func MonthBounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// FetchUsage returns the discounted orders, items and discount given to each staff member between from and to.
+// Former staff are included when they used the discount in that period.
+func FetchUsage(ctx context.Context, tx *sql.Tx, from, to time.Time, monthlyQuota int) ([]models.StaffDiscountUsage, error) {
+	usage := make([]models.StaffDiscountUsage, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				u.user_id,
+				u.email,
+				COUNT(co.order_id),
+				COALESCE(SUM(co.item_count), 0),
+				COALESCE(SUM(co.staff_discount), 0)
+			FROM "user" u
+			LEFT JOIN (
+				SELECT
+					co.order_id,
+					co.user_id,
+					co.staff_discount,
+					(SELECT COALESCE(SUM(oi.quantity - COALESCE(oi.cancelled_quantity, 0)), 0) FROM order_item oi WHERE oi.order_id = co.order_id) AS item_count
+				FROM customer_order co
+				WHERE co.staff_discount > 0
+					AND co.order_date >= $1 AND co.order_date < $2
+					AND co.order_status_id <> ALL($3)
+			) co ON co.user_id = u.user_id
+			WHERE u.staff_permission > 0 OR co.order_id IS NOT NULL
+			GROUP BY u.user_id, u.email
+			ORDER BY u.email ASC
+		`,
+		from,
+		to,
+		excludedStatuses,
+	)
+	if err != nil {
+		return usage, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.StaffDiscountUsage
+		err = rows.Scan(&s.UserID, &s.Email, &s.Orders, &s.Items, &s.DiscountAmount)
+		if err != nil {
+			return usage, err
+		}
+
+		s.ItemsRemaining = max(monthlyQuota-s.Items, 0)
+		usage = append(usage, s)
+	}
+
+	return usage, rows.Err()
+}
+
+// Validate checks whether a staff member may buy itemCount more items at the staff discount this month
+func Validate(ctx context.Context, tx *sql.Tx, userID string, itemCount int, at time.Time) (models.StaffDiscountValidation, error) {
+	var v models.StaffDiscountValidation
+
+	s, err := FetchSettings(ctx, tx)
+	if err != nil {
+		return v, err
+	}
+	v.Rate = s.Rate
+	v.MonthlyQuota = s.MonthlyQuota
+
+	var staffPermission int64
+	err = tx.QueryRowContext(ctx, `SELECT staff_permission FROM "user" WHERE user_id = $1::uuid`, userID).Scan(&staffPermission)
+	if err == sql.ErrNoRows || (err == nil && staffPermission <= 0) {
+		v.Reason = ReasonNotStaff
+		return v, nil
+	} else if err != nil {
+		return v, err
+	}
+
+	from, to := MonthBounds(at)
+	err = tx.QueryRowContext(
+		ctx,
+		`
+			SELECT COALESCE(SUM(oi.quantity - COALESCE(oi.cancelled_quantity, 0)), 0)
+			FROM customer_order co
+			JOIN order_item oi ON oi.order_id = co.order_id
+			WHERE co.user_id = $1::uuid
+				AND co.staff_discount > 0
+				AND co.order_date >= $2 AND co.order_date < $3
+				AND co.order_status_id <> ALL($4)
+		`,
+		userID,
+		from,
+		to,
+		excludedStatuses,
+	).Scan(&v.ItemsUsed)
+	if err != nil {
+		return v, err
+	}
+
+	v.ItemsRemaining = max(s.MonthlyQuota-v.ItemsUsed, 0)
+	if itemCount > v.ItemsRemaining {
+		v.Reason = ReasonQuotaExceeded
+		return v, nil
+	}
+
+	v.Eligible = true
+	return v, nil
+}
@@ -0,0 +1,108 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret from the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B, SHA1, truncated to our 6 digits
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestGenerateCodeRFCVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfcVectors {
+		if got := generateCode(key, v.unix/period); got != v.code {
+			t.Errorf("generateCode at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateRFCVectors(t *testing.T) {
+	for _, v := range rfcVectors {
+		step, ok := Validate(rfcSecret, v.code, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("Validate at %d rejected %s", v.unix, v.code)
+			continue
+		}
+		if step != v.unix/period {
+			t.Errorf("Validate at %d returned step %d, want %d", v.unix, step, v.unix/period)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	const unix = 1111111111
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{"previous step", -period, true},
+		{"next step", period, true},
+		{"two steps behind", -2 * period, false},
+		{"two steps ahead", 2 * period, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(rfcSecret, "050471", time.Unix(unix+tt.offset, 0)); ok != tt.want {
+				t.Errorf("Validate = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	tests := []struct {
+		name, secret, code string
+	}{
+		{"wrong code", rfcSecret, "123456"},
+		{"short code", rfcSecret, "05047"},
+		{"long code", rfcSecret, "0504710"},
+		{"invalid secret", "not base32!", "050471"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, at); ok {
+				t.Error("Validate accepted a bad code")
+			}
+		})
+	}
+}
+
+func TestValidateLowercaseSecret(t *testing.T) {
+	if _, ok := Validate("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050471", time.Unix(1111111111, 0)); !ok {
+		t.Error("Validate rejected a lowercase secret")
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	tests := map[string]bool{
+		"123456":    true,
+		"12345":     false,
+		"1234567":   false,
+		"12345a":    false,
+		"abcd-efgh": false,
+	}
+
+	for s, want := range tests {
+		if got := IsCode(s); got != want {
+			t.Errorf("IsCode(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
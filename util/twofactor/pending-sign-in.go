@@ -0,0 +1,75 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"server-api-admin/config"
+	"server-api-admin/util/redisclient"
+	"server-api-admin/util/tokens"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	pendingSignInLifetime    = 5 * time.Minute
+	pendingSignInMaxAttempts = 5
+)
+
+var ErrPendingSignInNotFound = errors.New("pending sign-in not found or expired")
+
+// Increments the attempt counter of an existing token and deletes the token once the limit is reached
+var recordFailedAttemptScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	local attempts = redis.call("HINCRBY", KEYS[1], "attempts", 1)
+	if attempts >= tonumber(ARGV[1]) then
+		redis.call("DEL", KEYS[1])
+	end
+	return attempts
+`)
+
+// CreatePendingSignIn stores a short-lived token proving the user has passed the password step
+func CreatePendingSignIn(ctx context.Context, userID string, rememberDevice bool) (string, error) {
+	token, tokenHash, err := tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	key := config.RedisKeyPrefixPendingSignIn + tokenHash
+	pipe := redisclient.Client.TxPipeline()
+	pipe.HSet(ctx, key, "userID", userID, "rememberDevice", rememberDevice, "attempts", 0)
+	pipe.Expire(ctx, key, pendingSignInLifetime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// FetchPendingSignIn returns the user ID the pending sign-in token was issued to, and whether they asked to be remembered
+func FetchPendingSignIn(ctx context.Context, token string) (string, bool, error) {
+	values, err := redisclient.Client.HMGet(ctx, config.RedisKeyPrefixPendingSignIn+tokens.Hash(token), "userID", "rememberDevice").Result()
+	if err != nil {
+		return "", false, err
+	}
+
+	userID, ok := values[0].(string)
+	if !ok {
+		return "", false, ErrPendingSignInNotFound
+	}
+
+	rememberDevice, _ := values[1].(string)
+	return userID, rememberDevice == "1", nil
+}
+
+// RecordFailedAttempt counts a wrong code against the token and discards it once too many have been tried
+func RecordFailedAttempt(ctx context.Context, token string) error {
+	key := config.RedisKeyPrefixPendingSignIn + tokens.Hash(token)
+	return recordFailedAttemptScript.Run(ctx, redisclient.Client, []string{key}, pendingSignInMaxAttempts).Err()
+}
+
+func DeletePendingSignIn(ctx context.Context, token string) error {
+	return redisclient.Client.Del(ctx, config.RedisKeyPrefixPendingSignIn+tokens.Hash(token)).Err()
+}
@@ -0,0 +1,263 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"server-api-admin/config"
+	"server-api-admin/util/password"
+	"server-api-admin/util/totp"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// Unambiguous characters used for recovery codes
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// IsEnabled reports whether the user has a confirmed TOTP enrollment
+func IsEnabled(ctx context.Context, tx *sql.Tx, userID string) (bool, error) {
+	var enabled bool
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT enabled FROM staff_totp WHERE user_id = $1::uuid",
+		userID,
+	).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes of the user
+func CountRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM staff_recovery_code WHERE user_id = $1::uuid AND used_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// BeginEnrollment stores a new unconfirmed secret for the user and returns it with its otpauth:// URI
+func BeginEnrollment(ctx context.Context, tx *sql.Tx, userID string) (string, string, error) {
+	var email string
+	err := tx.QueryRowContext(ctx, `SELECT email FROM "user" WHERE user_id = $1::uuid`, userID).Scan(&email)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO staff_totp (user_id, secret, enabled, last_used_step)
+			VALUES ($1::uuid, $2, FALSE, 0)
+			ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_used_step = 0
+			WHERE staff_totp.enabled = FALSE
+		`,
+		userID,
+		secret,
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return "", "", err
+	} else if n == 0 {
+		return "", "", ErrAlreadyEnabled
+	}
+
+	return secret, totp.URI(config.TOTPIssuer, email, secret), nil
+}
+
+// VerifyCode checks a TOTP code, or a recovery code when the input is not shaped like one.
+// Unconfirmed enrollments are only considered when allowPending is set.
+func VerifyCode(ctx context.Context, tx *sql.Tx, userID, code string, allowPending bool) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	var secret string
+	var enabled bool
+	var lastUsedStep int64
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT secret, enabled, last_used_step FROM staff_totp WHERE user_id = $1::uuid FOR UPDATE",
+		userID,
+	).Scan(&secret, &enabled, &lastUsedStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !enabled && !allowPending {
+		return false, nil
+	}
+
+	if !totp.IsCode(code) {
+		if !enabled {
+			return false, nil
+		}
+		return useRecoveryCode(ctx, tx, userID, code)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	// A code can only be used once
+	if !ok || step <= lastUsedStep {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE staff_totp SET last_used_step = $1 WHERE user_id = $2::uuid",
+		step,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Enable confirms the user's enrollment and returns a fresh set of recovery codes
+func Enable(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE staff_totp SET enabled = TRUE, enabled_at = NOW() WHERE user_id = $1::uuid",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReplaceRecoveryCodes(ctx, tx, userID)
+}
+
+// Disable removes the user's TOTP secret and recovery codes
+func Disable(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM staff_recovery_code WHERE user_id = $1::uuid", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM staff_totp WHERE user_id = $1::uuid", userID)
+	return err
+}
+
+// ReplaceRecoveryCodes invalidates any existing recovery codes and returns new ones.
+// Only their argon2 hashes are stored.
+func ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	_, err := tx.ExecContext(ctx, "DELETE FROM staff_recovery_code WHERE user_id = $1::uuid", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		completeHash, encodedSalt, err := password.GeneratePasswordHash(normaliseRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO staff_recovery_code (user_id, code_hash, salt) VALUES ($1::uuid, $2, $3)",
+			userID,
+			completeHash,
+			encodedSalt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func useRecoveryCode(ctx context.Context, tx *sql.Tx, userID, code string) (bool, error) {
+	code = normaliseRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false, nil
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT recovery_code_id, code_hash, salt FROM staff_recovery_code WHERE user_id = $1::uuid AND used_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	matchedID := 0
+	for rows.Next() {
+		var recoveryCodeID int
+		var codeHash, salt string
+		err = rows.Scan(&recoveryCodeID, &codeHash, &salt)
+		if err != nil {
+			return false, err
+		}
+
+		match, err := password.ComparePasswordWithHash(code, codeHash, salt)
+		if err == nil && match {
+			matchedID = recoveryCodeID
+			break
+		}
+	}
+	rows.Close()
+
+	if matchedID == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE staff_recovery_code SET used_at = NOW() WHERE recovery_code_id = $1", matchedID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+
+	// Shown to staff as XXXXX-XXXXX
+	half := recoveryCodeLength / 2
+	return string(b[:half]) + "-" + string(b[half:]), nil
+}
+
+func normaliseRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,28 @@
+package vouchers
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// Letters and digits that can't be mistaken for one another when read out or typed in
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const generatedCodeLength = 8
+
+// GenerateCode returns a random code, joined to the prefix with a dash when there is one
+func GenerateCode(prefix string) (string, error) {
+	b := make([]byte, generatedCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = codeAlphabet[n.Int64()]
+	}
+
+	if prefix == "" {
+		return string(b), nil
+	}
+	return prefix + "-" + string(b), nil
+}
@@ -0,0 +1,76 @@
+package vouchers
+
+import (
+	"context"
+	"database/sql"
+	"server-api-admin/models"
+	"time"
+)
+
+// FetchRedemptions lists the orders that used each voucher, for a single voucher when voucherID is set,
+// otherwise for every voucher of the campaign (or every voucher when campaign is empty).
+// Cancelled and refunded orders are listed but left out of the total.
+func FetchRedemptions(ctx context.Context, tx *sql.Tx, voucherID int, campaign string) ([]models.VoucherRedemptionReport, error) {
+	reports := make([]models.VoucherRedemptionReport, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				v.id,
+				v.coupon_code,
+				v.discount_rate,
+				COALESCE(v.campaign, ''),
+				co.order_id,
+				co.order_date,
+				co.order_status_id,
+				co.email,
+				COALESCE(co.storewide_pct_coupon_discount_amount, 0)
+			FROM vouchers_storewide_percentage_discount v
+			JOIN customer_order co ON co.storewide_pct_coupon_id = v.id
+			WHERE ($1 = 0 OR v.id = $1) AND ($2 = '' OR v.campaign = $2)
+			ORDER BY v.coupon_code ASC, co.order_date ASC
+		`,
+		voucherID,
+		campaign,
+	)
+	if err != nil {
+		return reports, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var report models.VoucherRedemptionReport
+		var redemption models.VoucherRedemption
+		var orderDate time.Time
+		err = rows.Scan(
+			&report.VoucherID,
+			&report.Code,
+			&report.Rate,
+			&report.Campaign,
+			&redemption.OrderID,
+			&orderDate,
+			&redemption.OrderStatusID,
+			&redemption.Email,
+			&redemption.DiscountAmount,
+		)
+		if err != nil {
+			return reports, err
+		}
+
+		redemption.OrderDate = orderDate.UnixMilli()
+
+		if len(reports) == 0 || reports[len(reports)-1].VoucherID != report.VoucherID {
+			report.Orders = make([]models.VoucherRedemption, 0)
+			reports = append(reports, report)
+		}
+		last := &reports[len(reports)-1]
+		last.Orders = append(last.Orders, redemption)
+		if !isExcludedStatus(redemption.OrderStatusID) {
+			last.TotalDiscount += redemption.DiscountAmount
+		}
+	}
+
+	return reports, rows.Err()
+}
@@ -0,0 +1,238 @@
+// Package vouchers manages storewide percentage discount vouchers.
+// Uses are counted from the orders that claimed a voucher, leaving out cancelled and refunded ones.
+package vouchers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"server-api-admin/models"
+	"server-api-admin/util/orders"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code for a broken unique constraint
+const uniqueViolation = "23505"
+
+var (
+	ErrCodeTaken   = errors.New("voucher code is already in use")
+	ErrHasBeenUsed = errors.New("voucher has been used, end its validity instead")
+
+	codePattern = regexp.MustCompile(`^[A-Z0-9-]{3,32}$`)
+)
+
+// Orders in these statuses don't count as uses
+var excludedStatusIDs = []int{orders.StatusCancelled, orders.StatusRefunded}
+
+var excludedStatuses = pq.Array(excludedStatusIDs)
+
+func isExcludedStatus(statusID int) bool {
+	return slices.Contains(excludedStatusIDs, statusID)
+}
+
+// NormaliseCode returns the code as stored, or an empty string when it isn't a valid code
+func NormaliseCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !codePattern.MatchString(code) {
+		return ""
+	}
+	return code
+}
+
+const voucherColumns = `
+	v.id,
+	v.coupon_code,
+	v.discount_rate,
+	v.valid_from,
+	v.valid_until,
+	COALESCE(v.max_uses, 0),
+	COALESCE(v.max_uses_per_customer, 0),
+	COALESCE(v.min_spend, 0),
+	COALESCE(v.campaign, ''),
+	v.created_at,
+	(
+		SELECT COUNT(*)
+		FROM customer_order co
+		WHERE co.storewide_pct_coupon_id = v.id AND co.order_status_id <> ALL($1)
+	)
+`
+
+func scanVoucher(row interface{ Scan(...any) error }) (models.Voucher, error) {
+	var v models.Voucher
+	var validFrom, createdAt time.Time
+	var validUntil sql.NullTime
+	err := row.Scan(
+		&v.ID,
+		&v.Code,
+		&v.Rate,
+		&validFrom,
+		&validUntil,
+		&v.MaxUses,
+		&v.MaxUsesPerCustomer,
+		&v.MinSpend,
+		&v.Campaign,
+		&createdAt,
+		&v.Uses,
+	)
+	if err != nil {
+		return v, err
+	}
+
+	v.ValidFrom = validFrom.UnixMilli()
+	if validUntil.Valid {
+		v.ValidUntil = validUntil.Time.UnixMilli()
+	}
+	v.CreatedAt = createdAt.UnixMilli()
+	return v, nil
+}
+
+// FetchVouchers returns every voucher, or only those of a campaign, newest first
+func FetchVouchers(ctx context.Context, tx *sql.Tx, campaign string) ([]models.Voucher, error) {
+	vouchers := make([]models.Voucher, 0)
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT `+voucherColumns+`
+		FROM vouchers_storewide_percentage_discount v
+		WHERE $2 = '' OR v.campaign = $2
+		ORDER BY v.created_at DESC, v.id DESC`,
+		excludedStatuses,
+		campaign,
+	)
+	if err != nil {
+		return vouchers, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return vouchers, err
+		}
+		vouchers = append(vouchers, v)
+	}
+
+	return vouchers, rows.Err()
+}
+
+// FetchVoucher returns a single voucher, locking it for the rest of the transaction
+func FetchVoucher(ctx context.Context, tx *sql.Tx, id int) (models.Voucher, error) {
+	return scanVoucher(tx.QueryRowContext(
+		ctx,
+		`SELECT `+voucherColumns+`
+		FROM vouchers_storewide_percentage_discount v
+		WHERE v.id = $2
+		FOR UPDATE`,
+		excludedStatuses,
+		id,
+	))
+}
+
+// Create stores a voucher and returns its ID. ErrCodeTaken is returned when the code exists.
+func Create(ctx context.Context, tx *sql.Tx, v models.Voucher, userID string) (int, error) {
+	var id int
+	err := tx.QueryRowContext(
+		ctx,
+		`
+			INSERT INTO vouchers_storewide_percentage_discount
+			(coupon_code, discount_rate, valid_from, valid_until, max_uses, max_uses_per_customer, min_spend, campaign, created_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), $9::uuid)
+			ON CONFLICT (coupon_code) DO NOTHING
+			RETURNING id
+		`,
+		v.Code,
+		v.Rate,
+		time.UnixMilli(v.ValidFrom),
+		nullTime(v.ValidUntil),
+		nullInt(v.MaxUses),
+		nullInt(v.MaxUsesPerCustomer),
+		v.MinSpend,
+		nullString(v.Campaign),
+		userID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrCodeTaken
+	}
+	return id, err
+}
+
+// Update saves every field of a voucher. ErrCodeTaken is returned when another voucher has the code.
+func Update(ctx context.Context, tx *sql.Tx, v models.Voucher) error {
+	// The unique index on coupon_code decides, a check beforehand could pass for two edits at once
+	_, err := tx.ExecContext(
+		ctx,
+		`
+			UPDATE vouchers_storewide_percentage_discount
+			SET
+				coupon_code = $1,
+				discount_rate = $2,
+				valid_from = $3,
+				valid_until = $4,
+				max_uses = $5,
+				max_uses_per_customer = $6,
+				min_spend = $7,
+				campaign = $8
+			WHERE id = $9
+		`,
+		v.Code,
+		v.Rate,
+		time.UnixMilli(v.ValidFrom),
+		nullTime(v.ValidUntil),
+		nullInt(v.MaxUses),
+		nullInt(v.MaxUsesPerCustomer),
+		v.MinSpend,
+		nullString(v.Campaign),
+		v.ID,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return ErrCodeTaken
+	}
+	return err
+}
+
+// IsClaimed reports whether any order, including cancelled and refunded ones, was placed with the voucher.
+// Those orders read the code and rate from the voucher, so neither may change from then on.
+func IsClaimed(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	var claimed bool
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM customer_order WHERE storewide_pct_coupon_id = $1)",
+		id,
+	).Scan(&claimed)
+	return claimed, err
+}
+
+// Delete removes a voucher no order has claimed. Claimed vouchers are kept so past orders still show them.
+func Delete(ctx context.Context, tx *sql.Tx, id int) error {
+	claimed, err := IsClaimed(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		return ErrHasBeenUsed
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM vouchers_storewide_percentage_discount WHERE id = $1", id)
+	return err
+}
+
+func nullTime(unixMilli int64) sql.NullTime {
+	if unixMilli == 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.UnixMilli(unixMilli), Valid: true}
+}
+
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}